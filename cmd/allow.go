@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdAllow returns the allow command.
+func NewCmdAllow() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "allow [path]",
+		Short: "Allow a project file (.awsmfa) to be applied",
+		Long: `Allow a project file (.awsmfa) to be applied.
+
+A project file can switch the profile and the role, so awsmfa ignores a project file until you allow it.
+awsmfa records the path and the hash of the content in ${HOME}/.awsmfa/allowed-projects,
+and ignores the project file again once it is changed. Review the file and run 'awsmfa allow' again.
+
+The path is a project file or a directory which has it. By default, it is the nearest project file from the current directory.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runAllowCmd,
+	}
+
+	return cmd
+}
+
+// NewCmdDeny returns the deny command.
+func NewCmdDeny() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deny [path]",
+		Short: "Revoke the permission of a project file (.awsmfa)",
+		Long: `Revoke the permission of a project file (.awsmfa) given by 'awsmfa allow'.
+
+The path is a project file or a directory which has it. By default, it is the nearest project file from the current directory.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runDenyCmd,
+	}
+
+	return cmd
+}
+
+func runAllowCmd(cmd *cobra.Command, args []string) error {
+	path, err := projectFileArg(args)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load project file %v: %w", path, err)
+	}
+	if err := allowProject(allowedProjectsFilePath(), path, data); err != nil {
+		return err
+	}
+	printCyan(fmt.Sprintf("Successfully allowed the project file: %v\n", path))
+	return nil
+}
+
+func runDenyCmd(cmd *cobra.Command, args []string) error {
+	path, err := projectFileArg(args)
+	if err != nil {
+		return err
+	}
+	if err := denyProject(allowedProjectsFilePath(), path); err != nil {
+		return err
+	}
+	printCyan(fmt.Sprintf("Successfully denied the project file: %v\n", path))
+	return nil
+}
+
+// projectFileArg returns the absolute path of the project file given by args, or the nearest one from the current directory.
+func projectFileArg(args []string) (string, error) {
+	if len(args) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the current directory: %w", err)
+		}
+		path := findProjectFile(wd)
+		if path == "" {
+			return "", fmt.Errorf("there is no project file (%v) in the current directory or its parents", projectFileName)
+		}
+		return path, nil
+	}
+
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %v: %w", args[0], err)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, projectFileName)
+	}
+	return path, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/ini.v1"
+)
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it to the file,
+// so that the file is never left half written even if awsmfa is interrupted.
+// perm is used only if the file doesn't exist. Otherwise the permission of the file is kept.
+// If the file is a symlink, such as a credentials file managed in a dotfiles repository, the file it points to is replaced and the symlink is kept.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %v: %w", path, err)
+		}
+		path = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	// The temporary file is removed unless it is renamed to the file.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to change permission of temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %v: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileWithDir writes data to the file atomically, creating its directory if needed.
+func writeFileWithDir(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %v: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
+
+// saveIniAtomic saves the ini file atomically.
+func saveIniAtomic(f *ini.File, path string) error {
+	data, err := iniBytes(f, path)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// saveIniAtomicWithDir saves the ini file atomically, creating its directory if needed.
+// perm is used only if the file doesn't exist.
+func saveIniAtomicWithDir(f *ini.File, path string, perm os.FileMode) error {
+	data, err := iniBytes(f, path)
+	if err != nil {
+		return err
+	}
+	return writeFileWithDir(path, data, perm)
+}
+
+func iniBytes(f *ini.File, path string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write %v: %w", path, err)
+	}
+	return buf.Bytes(), nil
+}
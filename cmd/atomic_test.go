@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials")
+
+	if err := writeFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("writeFileAtomic() perm = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The permission of the existing file is kept.
+	os.Chmod(path, 0640)
+	if err := writeFileAtomic(path, []byte("updated"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "updated" {
+		t.Errorf("writeFileAtomic() content = %v, want updated", string(got))
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("writeFileAtomic() perm = %v, want 0640", info.Mode().Perm())
+	}
+
+	// No temporary file is left.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("writeFileAtomic() left files: %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "credentials"), []byte("new"), 0600); err == nil {
+		t.Errorf("writeFileAtomic() error = nil, want error for missing directory")
+	}
+}
+
+func Test_writeFileAtomic_symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need a privilege on Windows")
+	}
+	dir := t.TempDir()
+	dotfiles := filepath.Join(dir, "dotfiles")
+	if err := os.Mkdir(dotfiles, 0700); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	target := filepath.Join(dotfiles, "credentials")
+	if err := os.WriteFile(target, []byte("old"), 0640); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	link := filepath.Join(dir, "credentials")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("updated"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("writeFileAtomic() replaced the symlink: %v, %v", info, err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "updated" {
+		t.Errorf("writeFileAtomic() content of the target = %v, want updated", string(got))
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0640 {
+		t.Errorf("writeFileAtomic() perm = %v, want 0640", info.Mode().Perm())
+	}
+
+	// The temporary file is created next to the target, not next to the symlink.
+	if entries, _ := os.ReadDir(dotfiles); len(entries) != 1 {
+		t.Errorf("writeFileAtomic() left files: %v", entries)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("writeFileAtomic() left files: %v", entries)
+	}
+}
+
+func Test_saveTemporaryToken_canceled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte("[existing]\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := saveTemporaryTokenFromGetSessionToken(ctx, nil, "existing", path, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("saveTemporaryTokenFromGetSessionToken() error = %v, want %v", err, context.Canceled)
+	}
+	if err := saveTemporaryTokenFromAssumeRole(ctx, nil, "existing", path, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("saveTemporaryTokenFromAssumeRole() error = %v, want %v", err, context.Canceled)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "[existing]\n" {
+		t.Errorf("the credentials file was modified: %v", string(got))
+	}
+}
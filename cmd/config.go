@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// config subcommand's input value
+var (
+	cliConfigProfile    string
+	cliConfigShowOrigin bool
+)
+
+// configKey is a setting key which awsmfa reads.
+type configKey struct {
+	name       string
+	section    string // Section in awsmfa's configuration file. Empty if the key can't be set globally.
+	perProfile bool   // The key can be set on the before-mfa profile in the shared config file.
+	validate   func(value string) error
+}
+
+// configKeys are all keys read by initUserDefault and setXxx selectors.
+var configKeys = []configKey{
+	{name: "credentials_file_path", section: "filepath", validate: validateNotEmpty},
+	{name: "config_file_path", section: "filepath", validate: validateNotEmpty},
+	{name: "suffix_of_before_mfa_profile", section: "default-value", validate: validateNotEmpty},
+	{name: "destination_profile_template", section: "default-value", validate: validateProfileTemplate},
+	{name: "mode", section: "default-value", validate: validateMode},
+	{name: "profile", section: "default-value", validate: validateNotEmpty},
+	{name: "mfa_serial", section: "default-value", perProfile: true, validate: validateNotEmpty},
+	{name: "duration_seconds", section: "default-value", perProfile: true, validate: validateDurationSeconds},
+	{name: "role_session_name", section: "default-value", perProfile: true, validate: validateNotEmpty},
+	{name: "region", section: "default-value", perProfile: true, validate: validateNotEmpty},
+	{name: "save_metadata", section: "default-value", validate: validateBool},
+	{name: "refresh_threshold", section: "default-value", perProfile: true, validate: validateThreshold},
+	{name: "awsmfa_role_arn", perProfile: true, validate: validateNotEmpty},
+	{name: "awsmfa_destination_profile", perProfile: true, validate: validateNotEmpty},
+	{name: "awsmfa_output_profile", perProfile: true, validate: validateNotEmpty},
+	{name: "awsmfa_output_credentials_file", perProfile: true, validate: validateNotEmpty},
+}
+
+// Limits of duration seconds of AWS STS API.
+const (
+	minDurationSeconds                int32 = 900
+	maxDurationSecondsGetSessionToken int32 = 129600
+	maxDurationSecondsAssumeRole      int32 = 43200
+)
+
+// NewCmdConfig returns the config command.
+func NewCmdConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Get, set, unset and list awsmfa's settings",
+		Long: fmt.Sprintf(`Get, set, unset and list awsmfa's settings.
+
+Without --profile, the settings are stored in awsmfa's configuration file (%v).
+With --profile, the settings are stored in the before-mfa profile of the shared config file.`, awsmfaCfgFilePath),
+	}
+
+	cmd.PersistentFlags().StringVarP(&cliConfigProfile, "profile", "p", "", "The profile whose before-mfa profile in the shared config file is read or written. If not specified, awsmfa's configuration file is used.")
+
+	getCmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Print the value of a key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigGetCmd,
+	}
+	setCmd := &cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Set the value of a key",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runConfigSetCmd,
+	}
+	unsetCmd := &cobra.Command{
+		Use:   "unset KEY",
+		Short: "Remove a key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigUnsetCmd,
+	}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List keys which have been set",
+		Args:  cobra.NoArgs,
+		RunE:  runConfigListCmd,
+	}
+	listCmd.Flags().BoolVar(&cliConfigShowOrigin, "show-origin", false, "Show the effective value of every parameter and where it comes from.")
+
+	cmd.AddCommand(getCmd, setCmd, unsetCmd, listCmd)
+
+	return cmd
+}
+
+func runConfigGetCmd(cmd *cobra.Command, args []string) error {
+	key, err := findConfigKey(args[0])
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	path, section, err := configTarget(key, cliConfigProfile)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	v, err := getConfigValue(path, section, key.name)
+	if err != nil {
+		return fmt.Errorf("failed to get %v: %w", key.name, err)
+	}
+
+	fmt.Println(v)
+	return nil
+}
+
+func runConfigSetCmd(cmd *cobra.Command, args []string) error {
+	key, err := findConfigKey(args[0])
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if err := key.validate(args[1]); err != nil {
+		return fmt.Errorf("invalid value for %v: %w", key.name, err)
+	}
+	path, section, err := configTarget(key, cliConfigProfile)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := setConfigValue(path, section, key.name, args[1]); err != nil {
+		return fmt.Errorf("failed to set %v: %w", key.name, err)
+	}
+
+	printCyan(fmt.Sprintf("Successfully set %v = %v in [%v] of %v\n", key.name, args[1], section, path))
+	return nil
+}
+
+func runConfigUnsetCmd(cmd *cobra.Command, args []string) error {
+	key, err := findConfigKey(args[0])
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	path, section, err := configTarget(key, cliConfigProfile)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := unsetConfigValue(path, section, key.name); err != nil {
+		return fmt.Errorf("failed to unset %v: %w", key.name, err)
+	}
+
+	printCyan(fmt.Sprintf("Successfully unset %v in [%v] of %v\n", key.name, section, path))
+	return nil
+}
+
+func runConfigListCmd(cmd *cobra.Command, args []string) error {
+	if cliConfigShowOrigin {
+		// Either file may not exist on a fresh machine.
+		cred, err := ini.LooseLoad(credentialsFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to load credentials file: %w", err)
+		}
+		cfg, err := ini.LooseLoad(configFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		awsmfaCfg, _ := ini.Load(awsmfaCfgFilePath)
+		projectCfg, _, err := loadProjectCfg(cliConfigProfile)
+		if err := skipProjectNotAllowed(err); err != nil {
+			return err
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Parameter", "Value", "Source"})
+		for _, p := range effectiveParams(cliConfigProfile, cred, cfg, awsmfaCfg, projectCfg) {
+			table.Append([]string{p.name, p.value, p.source})
+		}
+		table.Render()
+		return nil
+	}
+
+	for _, key := range configKeys {
+		path, section, err := configTarget(key, cliConfigProfile)
+		if err != nil {
+			continue
+		}
+		if v, err := getConfigValue(path, section, key.name); err == nil {
+			fmt.Printf("%v = %v\n", key.name, v)
+		}
+	}
+	return nil
+}
+
+// resolvedParam is a request param with the source it comes from.
+type resolvedParam struct {
+	name   string
+	value  string
+	source string
+}
+
+// effectiveParams resolves every request param with setXxx selectors.
+func effectiveParams(cliProfile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) []resolvedParam {
+	params := []resolvedParam{
+		{name: "credentials_file_path", value: credentialsFilePath, source: credentialsFilePathSource},
+		{name: "config_file_path", value: configFilePath, source: configFilePathSource},
+	}
+
+	profile, s := setProfile(cliProfile, defaultProfile, awsmfaCfg, projectCfg)
+	params = append(params, resolvedParam{name: "profile", value: profile, source: s})
+
+	beforeMFAProfile, s := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	params = append(params, resolvedParam{name: "before-mfa profile", value: beforeMFAProfile, source: s})
+
+	mode, s, err := setMode("", defaultMode, beforeMFAProfile, cred, cfg, awsmfaCfg, projectCfg)
+	if err != nil {
+		mode = "invalid"
+	}
+	params = append(params, resolvedParam{name: "mode", value: mode, source: s})
+
+	defaultDurationSeconds := defaultDurationSecondsGetSessionToken
+	if mode == "assume-role" {
+		defaultDurationSeconds = defaultDurationSecondsAssumeRole
+	}
+	durationSeconds, s, err := setDurationSeconds(0, defaultDurationSeconds, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	value := strconv.Itoa(int(durationSeconds))
+	if err != nil {
+		value = "invalid"
+	}
+	params = append(params, resolvedParam{name: "duration_seconds", value: value, source: s})
+
+	mfaSerial, s, err := setMFASerial("", defaultMFASerial, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	if err != nil {
+		mfaSerial, s = "(not specified)", "-"
+	}
+	params = append(params, resolvedParam{name: "mfa_serial", value: mfaSerial, source: s})
+
+	roleArn, s, err := setRoleArn("", beforeMFAProfile, cred, cfg, projectCfg)
+	if err != nil {
+		roleArn, s = "(not specified)", "-"
+	}
+	params = append(params, resolvedParam{name: "awsmfa_role_arn", value: roleArn, source: s})
+
+	roleSessionName, s := setRoleSessionName("", defaultRoleSessionName, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	params = append(params, resolvedParam{name: "role_session_name", value: roleSessionName, source: s})
+
+	endpointRegion, s := setEndpointRegion("", defaultEndpointRegion, profile, cred, cfg, awsmfaCfg, projectCfg)
+	params = append(params, resolvedParam{name: "region", value: endpointRegion, source: s})
+
+	outputProfile, s := setOutputProfile("", profile, beforeMFAProfile, cred, cfg)
+	params = append(params, resolvedParam{name: "awsmfa_output_profile", value: outputProfile, source: s})
+
+	outputFile, s := setOutputCredentialsFile("", credentialsFilePath, beforeMFAProfile, cred, cfg)
+	params = append(params, resolvedParam{name: "awsmfa_output_credentials_file", value: outputFile, source: s})
+
+	refreshThreshold, s, err := setRefreshThreshold(0, 0, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	value = refreshThreshold.String()
+	if err != nil {
+		value = "invalid"
+	}
+	params = append(params, resolvedParam{name: "refresh_threshold", value: value, source: s})
+
+	saveMetadata, s, err := setSaveMetadata(false, false, awsmfaCfg)
+	value = strconv.FormatBool(saveMetadata)
+	if err != nil {
+		value = "invalid"
+	}
+	params = append(params, resolvedParam{name: "save_metadata", value: value, source: s})
+
+	return params
+}
+
+// findConfigKey returns the configKey of given name.
+func findConfigKey(name string) (configKey, error) {
+	for _, k := range configKeys {
+		if k.name == name {
+			return k, nil
+		}
+	}
+	return configKey{}, fmt.Errorf("unknown key: %v", name)
+}
+
+// configTarget returns the file path and the section where given key is stored.
+// If profile is specified, the key is stored in the before-mfa profile of the shared config file.
+func configTarget(key configKey, profile string) (path string, section string, err error) {
+	if profile != "" {
+		if !key.perProfile {
+			return "ERROR", "ERROR", fmt.Errorf("the key %v can't be set per profile. Please remove --profile", key.name)
+		}
+		return configFilePath, "profile " + beforeMFAProfileOf(profile), nil
+	}
+	if key.section == "" {
+		return "ERROR", "ERROR", fmt.Errorf("the key %v can be set only per profile. Please specify --profile", key.name)
+	}
+	return awsmfaCfgFilePath, key.section, nil
+}
+
+// beforeMFAProfileOf loads the shared credentials/config file and awsmfa's configuration file, and returns the before-mfa profile of the profile.
+func beforeMFAProfileOf(profile string) string {
+	cred, err := ini.LooseLoad(credentialsFilePath)
+	if err != nil {
+		cred = ini.Empty()
+	}
+	cfg, err := ini.LooseLoad(configFilePath)
+	if err != nil {
+		cfg = ini.Empty()
+	}
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+	beforeMFAProfile, _ := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	return beforeMFAProfile
+}
+
+// getConfigValue returns the value of given key.
+func getConfigValue(path string, section string, key string) (string, error) {
+	f, err := ini.Load(path)
+	if err != nil {
+		return "ERROR", fmt.Errorf("failed to load %v: %w", path, err)
+	}
+	if !f.Section(section).HasKey(key) {
+		return "ERROR", fmt.Errorf("%v is not set in [%v] of %v", key, section, path)
+	}
+	return f.Section(section).Key(key).String(), nil
+}
+
+// setConfigValue writes given key to the file. If the file doesn't exist, setConfigValue creates it.
+func setConfigValue(path string, section string, key string, value string) error {
+	f := ini.Empty()
+	if _, err := os.Stat(path); err == nil {
+		if f, err = ini.Load(path); err != nil {
+			return fmt.Errorf("failed to load %v: %w", path, err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %v: %w", filepath.Dir(path), err)
+	}
+
+	f.Section(section).Key(key).SetValue(value)
+
+	if err := saveIniAtomic(f, path); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	return nil
+}
+
+// unsetConfigValue removes given key from the file.
+func unsetConfigValue(path string, section string, key string) error {
+	f, err := ini.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", path, err)
+	}
+	if !f.Section(section).HasKey(key) {
+		return fmt.Errorf("%v is not set in [%v] of %v", key, section, path)
+	}
+
+	f.Section(section).DeleteKey(key)
+
+	if err := saveIniAtomic(f, path); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	return nil
+}
+
+// validateNotEmpty checks if a value is not empty.
+func validateNotEmpty(value string) error {
+	if value == "" {
+		return fmt.Errorf("the value should not be empty")
+	}
+	return nil
+}
+
+// validateProfileTemplate checks if a value is a template of profile name such as {profile}-mfa.
+func validateProfileTemplate(value string) error {
+	if strings.Count(value, "{profile}") != 1 || value == "{profile}" {
+		return fmt.Errorf("the template should contain {profile} once with a prefix or suffix, such as {profile}-mfa or mfa-{profile}")
+	}
+	return nil
+}
+
+// validateThreshold checks if a value is a duration such as 10m or integer seconds.
+func validateThreshold(value string) error {
+	if _, err := parseThreshold(value); err != nil {
+		return fmt.Errorf("the value should be a duration such as 10m or integer seconds: %w", err)
+	}
+	return nil
+}
+
+// validateBool checks if a value is a boolean.
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("the value should be true or false")
+	}
+	return nil
+}
+
+// validateMode checks if a value is a valid action mode.
+func validateMode(value string) error {
+	if value != "get-session-token" && value != "assume-role" {
+		return fmt.Errorf("action mode should be \"get-session-token\" or \"assume-role\"")
+	}
+	return nil
+}
+
+// validateDurationSeconds checks if a value is in the range which at least one of AWS STS APIs accepts.
+func validateDurationSeconds(value string) error {
+	v, err := parseDurationSeconds(value)
+	if err != nil {
+		return fmt.Errorf("duration seconds should be integer seconds or a duration: %w", err)
+	}
+	if v < minDurationSeconds || v > maxDurationSecondsGetSessionToken {
+		return fmt.Errorf("duration seconds should be between %v and %v", minDurationSeconds, maxDurationSecondsGetSessionToken)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_findConfigKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "S01", key: "mode", wantErr: false},
+		{name: "S02", key: "awsmfa_role_arn", wantErr: false},
+		{name: "F01", key: "unknown_key💀", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findConfigKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findConfigKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.name != tt.key {
+				t.Errorf("findConfigKey() = %v, want %v", got.name, tt.key)
+			}
+		})
+	}
+}
+
+func Test_configTarget(t *testing.T) {
+	mode, _ := findConfigKey("mode")
+	mfaSerial, _ := findConfigKey("mfa_serial")
+	roleArn, _ := findConfigKey("awsmfa_role_arn")
+
+	tests := []struct {
+		name        string
+		key         configKey
+		profile     string
+		wantPath    string
+		wantSection string
+		wantErr     bool
+	}{
+		{name: "S01", key: mode, profile: "", wantPath: awsmfaCfgFilePath, wantSection: "default-value", wantErr: false},
+		{name: "S02", key: mfaSerial, profile: "", wantPath: awsmfaCfgFilePath, wantSection: "default-value", wantErr: false},
+		{name: "S03", key: mfaSerial, profile: "dev", wantPath: configFilePath, wantSection: "profile dev" + beforeMFASuffix, wantErr: false},
+		{name: "S04", key: roleArn, profile: "dev", wantPath: configFilePath, wantSection: "profile dev" + beforeMFASuffix, wantErr: false},
+		{name: "F01", key: mode, profile: "dev", wantPath: "ERROR", wantSection: "ERROR", wantErr: true},
+		{name: "F02", key: roleArn, profile: "", wantPath: "ERROR", wantSection: "ERROR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath, gotSection, err := configTarget(tt.key, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("configTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("configTarget() path = %v, want %v", gotPath, tt.wantPath)
+			}
+			if gotSection != tt.wantSection {
+				t.Errorf("configTarget() section = %v, want %v", gotSection, tt.wantSection)
+			}
+		})
+	}
+}
+
+func Test_setConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".awsmfa", "configuration")
+
+	if err := setConfigValue(path, "default-value", "mode", "assume-role"); err != nil {
+		t.Fatalf("setConfigValue() error = %v", err)
+	}
+	if got, err := getConfigValue(path, "default-value", "mode"); err != nil || got != "assume-role" {
+		t.Errorf("getConfigValue() = %v, %v, want assume-role", got, err)
+	}
+
+	if err := unsetConfigValue(path, "default-value", "mode"); err != nil {
+		t.Fatalf("unsetConfigValue() error = %v", err)
+	}
+	if _, err := getConfigValue(path, "default-value", "mode"); err == nil {
+		t.Errorf("getConfigValue() should return error after unset")
+	}
+	if err := unsetConfigValue(path, "default-value", "mode"); err == nil {
+		t.Errorf("unsetConfigValue() should return error for a key which is not set")
+	}
+}
+
+func Test_validateMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "S01", value: "get-session-token", wantErr: false},
+		{name: "S02", value: "assume-role", wantErr: false},
+		{name: "F01", value: "wrong-mode💀", wantErr: true},
+		{name: "F02", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMode(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("validateMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validateThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "S01", value: "10m", wantErr: false},
+		{name: "S02", value: "600", wantErr: false},
+		{name: "S03", value: "0", wantErr: false},
+		{name: "F01", value: "-10m", wantErr: true},
+		{name: "F02", value: "-600", wantErr: true},
+		{name: "F03", value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateThreshold(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("validateThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validateDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "S01", value: "900", wantErr: false},
+		{name: "S02", value: "129600", wantErr: false},
+		{name: "S03", value: "36h", wantErr: false},
+		{name: "F01", value: "899", wantErr: true},
+		{name: "F02", value: "129601", wantErr: true},
+		{name: "F03", value: "one hour", wantErr: true},
+		{name: "F04", value: "14m59s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDurationSeconds(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("validateDurationSeconds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// doctor subcommand's input value
+var (
+	cliDoctorProfile string
+)
+
+// Formats of ARNs awsmfa uses.
+var (
+	mfaSerialArnRegexp = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:iam::\d{12}:mfa/[\w+=,.@/-]+$`)
+	mfaSerialRegexp    = regexp.MustCompile(`^[\w+=/:,.@-]{9,256}$`)
+	roleArnRegexp      = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+)
+
+// Environment variables which take priority over the profile in loading credentials.
+var shadowingEnvs = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+// checkStatus is a result of a diagnosis.
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkPass:
+		return "PASS"
+	case checkWarn:
+		return "WARN"
+	case checkFail:
+		return "FAIL"
+	}
+	return "unknown checkStatus"
+}
+
+// checkResult is a result of each check of doctor.
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+}
+
+// NewCmdDoctor returns the doctor command.
+func NewCmdDoctor() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose MFA setup of a profile without calling AWS",
+		Long: `Diagnose MFA setup of a profile without calling AWS.
+
+awsmfa runs every precondition check at once and reports pass, warn or fail per check.`,
+		Args: cobra.NoArgs,
+		RunE: runDoctorCmd,
+	}
+
+	cmd.Flags().StringVarP(&cliDoctorProfile, "profile", "p", "", "The profile to diagnose. The default value is 'default'")
+
+	return cmd
+}
+
+func runDoctorCmd(cmd *cobra.Command, args []string) error {
+	results := []checkResult{}
+
+	cred, err := ini.LooseLoad(credentialsFilePath)
+	if err != nil {
+		results = append(results, checkResult{name: "credentials file", status: checkFail, detail: fmt.Sprintf("failed to load %v (%v): %v", credentialsFilePath, credentialsFilePathSource, err)})
+		cred = ini.Empty()
+	}
+	cfg, err := ini.LooseLoad(configFilePath)
+	if err != nil {
+		results = append(results, checkResult{name: "config file", status: checkFail, detail: fmt.Sprintf("failed to load %v (%v): %v", configFilePath, configFilePathSource, err)})
+		cfg = ini.Empty()
+	}
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+
+	projectCfg, projectFile, err := loadProjectCfg(cliDoctorProfile)
+	if errors.Is(err, errProjectNotAllowed) {
+		results = append(results, checkResult{name: "project file", status: checkWarn, detail: err.Error()})
+	} else if err != nil {
+		results = append(results, checkResult{name: "project file", status: checkFail, detail: err.Error()})
+	} else if projectCfg != nil {
+		results = append(results, checkResult{name: "project file", status: checkPass, detail: projectFile})
+	}
+
+	profile, _ := setProfile(cliDoctorProfile, defaultProfile, awsmfaCfg, projectCfg)
+	fmt.Printf("Diagnose the profile \"%v\" ...\n", profile)
+
+	results = append(results, diagnose(profile, cred, cfg, awsmfaCfg, projectCfg)...)
+	if runtime.GOOS != "windows" {
+		// Check the file where temporary credentials are saved, which may differ from the shared credentials file.
+		beforeMFAProfile, _ := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+		outputFile, _ := setOutputCredentialsFile("", credentialsFilePath, beforeMFAProfile, cred, cfg)
+		results = append(results, checkFilePermission(outputFile))
+	}
+
+	failed := 0
+	for _, r := range results {
+		printCheckResult(r)
+		if r.status == checkFail {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v checks failed", failed, len(results))
+	}
+	printCyan(fmt.Sprintf("No problem found in %v checks\n", len(results)))
+	return nil
+}
+
+// diagnose runs all checks which need only loaded files and environment variables.
+func diagnose(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) []checkResult {
+	results := []checkResult{}
+
+	beforeMFAProfile, _ := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	results = append(results, checkBeforeMFASections(beforeMFAProfile, cred, cfg)...)
+	results = append(results, checkLongTermKeys(beforeMFAProfile, cred, cfg))
+
+	mode, _, err := setMode("", defaultMode, beforeMFAProfile, cred, cfg, awsmfaCfg, projectCfg)
+	if err != nil {
+		results = append(results, checkResult{name: "mode", status: checkFail, detail: err.Error()})
+		mode = defaultMode
+	} else {
+		results = append(results, checkResult{name: "mode", status: checkPass, detail: mode})
+	}
+
+	results = append(results, checkMFASerial(beforeMFAProfile, cred, cfg, awsmfaCfg))
+	if mode == "assume-role" {
+		results = append(results, checkRoleArn(beforeMFAProfile, cred, cfg, projectCfg))
+	}
+	results = append(results, checkDurationSeconds(mode, beforeMFAProfile, cred, cfg, awsmfaCfg))
+	results = append(results, checkShadowingEnvs())
+	results = append(results, checkConfigurationKeys(awsmfaCfg)...)
+
+	return results
+}
+
+// checkBeforeMFASections checks if the before-mfa profile exists in the shared credentials or config file.
+func checkBeforeMFASections(beforeMFAProfile string, cred *ini.File, cfg *ini.File) []checkResult {
+	name := "before-mfa profile"
+
+	found := []string{}
+	if _, err := cred.GetSection(beforeMFAProfile); err == nil {
+		found = append(found, fmt.Sprintf("[%v] in %v", beforeMFAProfile, credentialsFilePath))
+	}
+	if _, err := cfg.GetSection("profile " + beforeMFAProfile); err == nil {
+		found = append(found, fmt.Sprintf("[profile %v] in %v", beforeMFAProfile, configFilePath))
+	}
+	if len(found) == 0 {
+		return []checkResult{{name: name, status: checkFail, detail: fmt.Sprintf("neither [%v] in %v nor [profile %v] in %v is found", beforeMFAProfile, credentialsFilePath, beforeMFAProfile, configFilePath)}}
+	}
+	return []checkResult{{name: name, status: checkPass, detail: strings.Join(found, ", ")}}
+}
+
+// checkLongTermKeys checks if aws-sdk-go-v2 can resolve the source credentials of the before-mfa profile.
+func checkLongTermKeys(beforeMFAProfile string, cred *ini.File, cfg *ini.File) checkResult {
+	name := "source credentials"
+
+	source, err := credentialSourceOf(beforeMFAProfile, cred, cfg)
+	if err != nil {
+		return checkResult{name: name, status: checkFail, detail: err.Error()}
+	}
+	if sec, err := cred.GetSection(beforeMFAProfile); err == nil && sec.HasKey("aws_session_token") {
+		return checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("[%v] has aws_session_token. The keys seem to be temporary credentials", beforeMFAProfile)}
+	}
+	return checkResult{name: name, status: checkPass, detail: source}
+}
+
+// checkMFASerial checks if the mfa_serial is specified and well-formed.
+func checkMFASerial(beforeMFAProfile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) checkResult {
+	name := "mfa_serial"
+
+	serial, source, err := setMFASerial("", defaultMFASerial, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	if err != nil {
+		return checkResult{name: name, status: checkFail, detail: "mfa_serial is not specified"}
+	}
+	if mfaSerialArnRegexp.MatchString(serial) {
+		return checkResult{name: name, status: checkPass, detail: fmt.Sprintf("%v (%v)", serial, source)}
+	}
+	if mfaSerialRegexp.MatchString(serial) {
+		return checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("%v (%v) is not an ARN of a virtual device. Please make sure it is a serial number of a hardware device", serial, source)}
+	}
+	return checkResult{name: name, status: checkFail, detail: fmt.Sprintf("%v (%v) is neither an ARN such as arn:aws:iam::123456789012:mfa/user nor a serial number", serial, source)}
+}
+
+// checkRoleArn checks if the awsmfa_role_arn is specified and well-formed.
+func checkRoleArn(beforeMFAProfile string, cred *ini.File, cfg *ini.File, projectCfg *ini.File) checkResult {
+	name := "awsmfa_role_arn"
+
+	roleArn, source, err := setRoleArn("", beforeMFAProfile, cred, cfg, projectCfg)
+	if err != nil {
+		return checkResult{name: name, status: checkFail, detail: "awsmfa_role_arn is not specified"}
+	}
+	if !roleArnRegexp.MatchString(roleArn) {
+		return checkResult{name: name, status: checkFail, detail: fmt.Sprintf("%v (%v) is not an ARN such as arn:aws:iam::123456789012:role/role-name", roleArn, source)}
+	}
+	return checkResult{name: name, status: checkPass, detail: fmt.Sprintf("%v (%v)", roleArn, source)}
+}
+
+// checkDurationSeconds checks if the duration seconds is in the range of the mode.
+func checkDurationSeconds(mode string, beforeMFAProfile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) checkResult {
+	name := "duration_seconds"
+
+	defaultValue, maxValue := defaultDurationSecondsGetSessionToken, maxDurationSecondsGetSessionToken
+	if mode == "assume-role" {
+		defaultValue, maxValue = defaultDurationSecondsAssumeRole, maxDurationSecondsAssumeRole
+	}
+
+	duration, source, err := setDurationSeconds(0, defaultValue, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	if err != nil {
+		return checkResult{name: name, status: checkFail, detail: err.Error()}
+	}
+	if duration < minDurationSeconds || duration > maxValue {
+		return checkResult{name: name, status: checkFail, detail: fmt.Sprintf("%v (%v) is out of range for %v: %v - %v", duration, source, mode, minDurationSeconds, maxValue)}
+	}
+	return checkResult{name: name, status: checkPass, detail: fmt.Sprintf("%v (%v)", duration, source)}
+}
+
+// checkFilePermission checks if the file is not readable by other users.
+func checkFilePermission(path string) checkResult {
+	name := "credentials file permission"
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// A profile in the config file doesn't need the credentials file. awsmfa creates it with 0600 when it saves temporary credentials.
+		return checkResult{name: name, status: checkPass, detail: fmt.Sprintf("%v doesn't exist yet. It will be created with 0600", path)}
+	}
+	if err != nil {
+		return checkResult{name: name, status: checkFail, detail: fmt.Sprintf("failed to stat %v: %v", path, err)}
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("%v is accessible by other users (%v). Please run 'chmod 600 %v'", path, perm, path)}
+	}
+	return checkResult{name: name, status: checkPass, detail: info.Mode().Perm().String()}
+}
+
+// checkShadowingEnvs checks if environment variables take priority over the profile in loading credentials.
+func checkShadowingEnvs() checkResult {
+	name := "environment variables"
+
+	found := []string{}
+	for _, e := range shadowingEnvs {
+		if _, exists := os.LookupEnv(e); exists {
+			found = append(found, e)
+		}
+	}
+	if len(found) > 0 {
+		return checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("%v is set. It is used instead of the keys of the before-mfa profile", found)}
+	}
+	return checkResult{name: name, status: checkPass, detail: "no variable shadows the profile"}
+}
+
+// checkConfigurationKeys checks if all keys in awsmfa's configuration file are recognized.
+func checkConfigurationKeys(awsmfaCfg *ini.File) []checkResult {
+	name := "configuration keys"
+
+	if awsmfaCfg == nil {
+		return []checkResult{{name: name, status: checkPass, detail: "no configuration file. The build in default values are used"}}
+	}
+
+	results := []checkResult{}
+	for _, sec := range awsmfaCfg.Sections() {
+		// Keys of [profile-mapping] are names of before-mfa profiles.
+		if sec.Name() == "profile-mapping" {
+			continue
+		}
+		for _, k := range sec.KeyStrings() {
+			if key, err := findConfigKey(k); err != nil || key.section != sec.Name() {
+				results = append(results, checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("%v in [%v] is not recognized by awsmfa", k, sec.Name())})
+			}
+		}
+	}
+	if len(results) == 0 {
+		return []checkResult{{name: name, status: checkPass, detail: "all keys are recognized"}}
+	}
+	return results
+}
+
+// printCheckResult prints a checkResult with the color of its status.
+func printCheckResult(r checkResult) {
+	c := color.New(color.FgCyan)
+	switch r.status {
+	case checkWarn:
+		c = color.New(color.FgYellow)
+	case checkFail:
+		c = color.New(color.FgRed)
+	}
+	c.Printf("[%v] ", r.status)
+	fmt.Printf("%v: %v\n", r.name, r.detail)
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+func Test_checkLongTermKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		wantStatus checkStatus
+	}{
+		{name: "S01: pass", profile: "ok", wantStatus: checkPass},
+		{name: "S02: temporary credentials", profile: "temporary", wantStatus: checkWarn},
+		{name: "S03: credential_process in config file", profile: "process", wantStatus: checkPass},
+		{name: "S04: source_profile in config file", profile: "chain", wantStatus: checkPass},
+		{name: "F01: no secret access key", profile: "nokey", wantStatus: checkFail},
+		{name: "F02: no profile", profile: "nothing", wantStatus: checkFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/doctor_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			cfg, err := ini.Load("testdata/doctor_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			if got := checkLongTermKeys(tt.profile+beforeMFASuffix, cred, cfg); got.status != tt.wantStatus {
+				t.Errorf("checkLongTermKeys() = %+v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_checkBeforeMFASections(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		wantStatus checkStatus
+	}{
+		{name: "S01: both files", profile: "ok", wantStatus: checkPass},
+		{name: "S02: only credentials file", profile: "temporary", wantStatus: checkPass},
+		{name: "S03: only config file", profile: "process", wantStatus: checkPass},
+		{name: "F01: no profile", profile: "nothing", wantStatus: checkFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/doctor_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/doctor_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			if got := checkBeforeMFASections(tt.profile+beforeMFASuffix, cred, cfg); len(got) != 1 || got[0].status != tt.wantStatus {
+				t.Errorf("checkBeforeMFASections() = %+v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_checkMFASerial_checkRoleArn_checkDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       string
+		mode          string
+		wantMFASerial checkStatus
+		wantRoleArn   checkStatus
+		wantDuration  checkStatus
+	}{
+		{name: "S01: pass", profile: "ok", mode: "assume-role", wantMFASerial: checkPass, wantRoleArn: checkPass, wantDuration: checkPass},
+		{name: "S02: hardware device and too long duration for assume-role", profile: "hardware", mode: "assume-role", wantMFASerial: checkWarn, wantRoleArn: checkFail, wantDuration: checkFail},
+		{name: "S03: long duration for get-session-token", profile: "hardware", mode: "get-session-token", wantMFASerial: checkWarn, wantRoleArn: checkFail, wantDuration: checkPass},
+		{name: "F01: broken", profile: "broken", mode: "get-session-token", wantMFASerial: checkFail, wantRoleArn: checkFail, wantDuration: checkFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/doctor_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/doctor_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			if got := checkMFASerial(tt.profile+beforeMFASuffix, cred, cfg, nil); got.status != tt.wantMFASerial {
+				t.Errorf("checkMFASerial() = %+v, want %v", got, tt.wantMFASerial)
+			}
+			if got := checkRoleArn(tt.profile+beforeMFASuffix, cred, cfg, nil); got.status != tt.wantRoleArn {
+				t.Errorf("checkRoleArn() = %+v, want %v", got, tt.wantRoleArn)
+			}
+			if got := checkDurationSeconds(tt.mode, tt.profile+beforeMFASuffix, cred, cfg, nil); got.status != tt.wantDuration {
+				t.Errorf("checkDurationSeconds() = %+v, want %v", got, tt.wantDuration)
+			}
+		})
+	}
+}
+
+func Test_checkFilePermission(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		perm       os.FileMode
+		missing    bool
+		wantStatus checkStatus
+	}{
+		{name: "S01: owner only", perm: 0600, wantStatus: checkPass},
+		{name: "S02: readable by others", perm: 0644, wantStatus: checkWarn},
+		{name: "S03: not created yet", missing: true, wantStatus: checkPass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(dir, tt.perm.String())
+			if tt.missing {
+				p = filepath.Join(dir, "missing")
+				if got := checkFilePermission(p); got.status != tt.wantStatus {
+					t.Errorf("checkFilePermission() = %+v, want %v", got, tt.wantStatus)
+				}
+				return
+			}
+			if err := os.WriteFile(p, []byte{}, tt.perm); err != nil {
+				t.Fatalf("failed to create test data: %v", err)
+			}
+			os.Chmod(p, tt.perm)
+
+			if got := checkFilePermission(p); got.status != tt.wantStatus {
+				t.Errorf("checkFilePermission() = %+v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_checkShadowingEnvs(t *testing.T) {
+	tests := []struct {
+		name       string
+		existsEnv  bool
+		wantStatus checkStatus
+	}{
+		{name: "S01: no env", existsEnv: false, wantStatus: checkPass},
+		{name: "S02: AWS_ACCESS_KEY_ID", existsEnv: true, wantStatus: checkWarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+			if tt.existsEnv {
+				os.Setenv("AWS_ACCESS_KEY_ID", "env")
+			}
+
+			if got := checkShadowingEnvs(); got.status != tt.wantStatus {
+				t.Errorf("checkShadowingEnvs() = %+v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_checkConfigurationKeys(t *testing.T) {
+	awsmfaCfg, err := ini.Load("testdata/doctor_awsmfaConfiguration")
+	if err != nil {
+		t.Errorf("failed to load test data: %v", err)
+	}
+
+	got := checkConfigurationKeys(awsmfaCfg)
+	if len(got) != 1 || got[0].status != checkWarn {
+		t.Errorf("checkConfigurationKeys() = %+v, want one warning for endpoint_region", got)
+	}
+
+	if got := checkConfigurationKeys(nil); len(got) != 1 || got[0].status != checkPass {
+		t.Errorf("checkConfigurationKeys() = %+v, want pass", got)
+	}
+}
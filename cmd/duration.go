@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Layouts of --until. A layout without date means the next time of the day.
+var untilLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02T15:04"}
+var untilClockLayouts = []string{"15:04", "15:04:05"}
+
+// parseDurationSeconds parses integer seconds such as 3600, or a duration such as 12h, 90m and 1h30m.
+func parseDurationSeconds(v string) (int32, error) {
+	if sec, err := strconv.ParseInt(v, 10, 32); err == nil {
+		return int32(sec), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%v is neither integer seconds nor a duration such as 12h, 90m or 1h30m", v)
+	}
+	if d.Seconds() > math.MaxInt32 {
+		return 0, fmt.Errorf("%v is too long", v)
+	}
+	return int32(d.Seconds()), nil
+}
+
+// parseUntil returns the duration seconds from now to the time, such as 18:30 or 2026-10-16T19:00+09:00.
+// A time of the day means the next one, so 08:00 at 18:30 means 08:00 tomorrow.
+func parseUntil(v string, now time.Time) (int32, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, v, now.Location()); err == nil {
+			return secondsUntil(t, now)
+		}
+	}
+	for _, layout := range untilClockLayouts {
+		if c, err := time.Parse(layout, v); err == nil {
+			t := time.Date(now.Year(), now.Month(), now.Day(), c.Hour(), c.Minute(), c.Second(), 0, now.Location())
+			if !t.After(now) {
+				t = t.AddDate(0, 0, 1)
+			}
+			return secondsUntil(t, now)
+		}
+	}
+	return 0, fmt.Errorf("%v is neither a time of the day such as 18:30 nor a date time such as 2026-10-16T19:00+09:00", v)
+}
+
+// secondsUntil returns the duration seconds from now to the time.
+func secondsUntil(t time.Time, now time.Time) (int32, error) {
+	d := t.Sub(now)
+	if d <= 0 {
+		return 0, fmt.Errorf("%v is in the past", t.Format(time.RFC3339))
+	}
+	if d.Seconds() > math.MaxInt32 {
+		return 0, fmt.Errorf("%v is too far", t.Format(time.RFC3339))
+	}
+	return int32(d.Seconds()), nil
+}
+
+// durationSecondsLimits returns the range of duration seconds which AWS STS API of the mode accepts.
+func durationSecondsLimits(mode string) (min int32, max int32) {
+	if mode == "assume-role" {
+		return minDurationSeconds, maxDurationSecondsAssumeRole
+	}
+	return minDurationSeconds, maxDurationSecondsGetSessionToken
+}
+
+// clampDurationSeconds clamps the duration seconds to the range of the mode. ok is false if it is clamped.
+func clampDurationSeconds(duration int32, mode string) (clamped int32, ok bool) {
+	min, max := durationSecondsLimits(mode)
+	switch {
+	case duration < min:
+		return min, false
+	case duration > max:
+		return max, false
+	}
+	return duration, true
+}
+
+// durationSecondsValue is a flag value of duration seconds which accepts both integer seconds and a duration.
+type durationSecondsValue struct {
+	p *int32
+}
+
+func (v durationSecondsValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v.p))
+}
+
+func (v durationSecondsValue) Set(s string) error {
+	d, err := parseDurationSeconds(s)
+	if err != nil {
+		return err
+	}
+	*v.p = d
+	return nil
+}
+
+func (v durationSecondsValue) Type() string {
+	return "duration"
+}
+
+// untilValue is a flag value which sets duration seconds from now to the specified time.
+type untilValue struct {
+	p *int32
+}
+
+func (v untilValue) String() string {
+	return ""
+}
+
+func (v untilValue) Set(s string) error {
+	d, err := parseUntil(s, time.Now())
+	if err != nil {
+		return err
+	}
+	*v.p = d
+	return nil
+}
+
+func (v untilValue) Type() string {
+	return "time"
+}
+
+// checkDurationFlags returns an error if both --duration-seconds and --until are specified.
+func checkDurationFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("duration-seconds") && cmd.Flags().Changed("until") {
+		return fmt.Errorf("--duration-seconds and --until cannot be used together")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int32
+		wantErr bool
+	}{
+		{name: "S01: seconds", value: "3600", want: 3600},
+		{name: "S02: hours", value: "12h", want: 43200},
+		{name: "S03: minutes", value: "90m", want: 5400},
+		{name: "S04: hours and minutes", value: "1h30m", want: 5400},
+		{name: "S05: leading zero is decimal", value: "0900", want: 900},
+		{name: "S06: leading zero is not octal", value: "010", want: 10},
+		{name: "F01: empty", value: "", wantErr: true},
+		{name: "F02: unknown unit", value: "1d", wantErr: true},
+		{name: "F03: too long", value: "1000000h", wantErr: true},
+		{name: "F04: hexadecimal", value: "0x384", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDurationSeconds(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDurationSeconds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDurationSeconds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseUntil(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, jst)
+	tests := []struct {
+		name    string
+		value   string
+		want    int32
+		wantErr bool
+	}{
+		{name: "S01: time of today", value: "18:30", want: 30600},
+		{name: "S02: time of tomorrow", value: "08:00", want: 79200},
+		{name: "S03: date time with offset", value: "2026-10-16T19:00+09:00", want: 32400},
+		{name: "S04: RFC3339", value: "2026-10-16T10:00:00Z", want: 32400},
+		{name: "S05: date time in local time", value: "2026-10-17T10:00", want: 86400},
+		{name: "F01: past", value: "2026-10-16T09:00+09:00", wantErr: true},
+		{name: "F02: invalid", value: "tonight", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUntil(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUntil() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseUntil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_clampDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int32
+		mode     string
+		want     int32
+		wantOK   bool
+	}{
+		{name: "S01: in range of get-session-token", duration: 129600, mode: "get-session-token", want: 129600, wantOK: true},
+		{name: "S02: in range of assume-role", duration: 43200, mode: "assume-role", want: 43200, wantOK: true},
+		{name: "S03: too short", duration: 600, mode: "get-session-token", want: 900, wantOK: false},
+		{name: "S04: too long for get-session-token", duration: 200000, mode: "get-session-token", want: 129600, wantOK: false},
+		{name: "S05: too long for assume-role", duration: 43201, mode: "assume-role", want: 43200, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := clampDurationSeconds(tt.duration, tt.mode)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("clampDurationSeconds() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
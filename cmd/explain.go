@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// NewCmdExplain returns the explain command.
+func NewCmdExplain() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Show how request params are resolved without calling AWS STS",
+		Long: `Show how request params are resolved without calling AWS STS.
+
+awsmfa evaluates every layer of the priority of each request param (cli option, environment variable,
+before-mfa and after-mfa profile in the shared credentials/config file, awsmfa's configuration file and build in default value),
+and shows what each layer holds and which one is used.`,
+		Args: cobra.NoArgs,
+		RunE: runExplainCmd,
+	}
+
+	addParamFlags(cmd)
+
+	return cmd
+}
+
+func runExplainCmd(cmd *cobra.Command, args []string) error {
+	if err := checkDurationFlags(cmd); err != nil {
+		return err
+	}
+
+	cred, err := ini.Load(credentialsFilePath)
+	if err != nil {
+		printBlue(fmt.Sprintf("[Tips] Failed to load credentials file. Its layers are shown as not set: %v\n", err))
+		cred = ini.Empty()
+	}
+	cfg, err := ini.Load(configFilePath)
+	if err != nil {
+		printBlue(fmt.Sprintf("[Tips] Failed to load config file. Its layers are shown as not set: %v\n", err))
+		cfg = ini.Empty()
+	}
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+	projectCfg, projectFile, err := loadProjectCfg(cliProfile)
+	if err := skipProjectNotAllowed(err); err != nil {
+		return err
+	}
+
+	fmt.Printf("awsmfa's configuration file: %v (%v)\n", awsmfaCfgFilePath, awsmfaCfgFileSource)
+	fmt.Printf("shared credentials file: %v (%v)\n", credentialsFilePath, credentialsFilePathSource)
+	fmt.Printf("shared config file: %v (%v)\n", configFilePath, configFilePathSource)
+	if projectCfg != nil {
+		fmt.Printf("project file: %v\n", projectFile)
+	}
+	fmt.Printf("Request params are resolved as follows. Upper layers take priority.\n")
+	printExplanations(explainParams(cred, cfg, awsmfaCfg, projectCfg))
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// hookScripts are shell scripts which ask to refresh temporary credentials when you enter a project whose session has expired.
+// They find the project and its session by 'awsmfa prompt --project-only', so the shell pays only a few milliseconds per cd.
+var hookScripts = map[string]string{
+	"bash": `_awsmfa_hook() {
+  [ "$PWD" = "${_awsmfa_last_dir-}" ] && return
+  _awsmfa_last_dir=$PWD
+  local segment answer
+  segment=$(command awsmfa prompt --project-only 2>/dev/null) || return
+  case "$segment" in
+  *" EXPIRED")
+    read -r -p "awsmfa: the session of ${segment% EXPIRED} has expired. Refresh it now? [y/N] " answer
+    case "$answer" in [yY]*) command awsmfa ;; esac
+    ;;
+  esac
+}
+case ";${PROMPT_COMMAND-};" in
+*";_awsmfa_hook;"*) ;;
+*) PROMPT_COMMAND="_awsmfa_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	"zsh": `_awsmfa_hook() {
+  local segment answer
+  segment=$(command awsmfa prompt --project-only 2>/dev/null) || return
+  if [[ $segment == *" EXPIRED" ]]; then
+    read -r "answer?awsmfa: the session of ${segment% EXPIRED} has expired. Refresh it now? [y/N] "
+    [[ $answer == [yY]* ]] && command awsmfa
+  fi
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _awsmfa_hook
+`,
+}
+
+// NewCmdHook returns the hook command.
+func NewCmdHook() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook [bash|zsh]",
+		Short: "Generate a shell hook to refresh the session of a project when you cd into it",
+		Long: `Generate a shell hook to refresh the session of a project when you cd into it.
+
+When you enter a directory which has a project file (.awsmfa) in it or its parents, and the session of the profile
+has expired, the hook asks you to refresh it and runs awsmfa with the project file.
+A project file is used only after you allow it by 'awsmfa allow'.
+
+Bash:
+
+	$ echo 'eval "$(awsmfa hook bash)"' >> ~/.bashrc
+
+Zsh:
+
+	$ echo 'eval "$(awsmfa hook zsh)"' >> ~/.zshrc
+`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh"},
+		Args:                  cobra.ExactValidArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(cmd.OutOrStdout(), hookScripts[args[0]])
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/ini.v1"
+)
+
+// Default answers of the init wizard.
+const (
+	initDefaultEndpointRegion = "us-east-1"
+	initDefaultRegion         = "us-east-1"
+)
+
+// initAnswers are the answers of the init wizard.
+type initAnswers struct {
+	profile         string
+	mode            string
+	accessKeyID     string
+	secretAccessKey string
+	mfaSerial       string
+	roleArn         string
+	endpointRegion  string
+	region          string
+	setAsDefault    bool
+}
+
+// initTarget is a file to be updated by the init wizard.
+type initTarget struct {
+	path     string
+	perm     os.FileMode
+	sections []iniSection
+}
+
+// iniSection is a section to be merged into an ini file.
+type iniSection struct {
+	name string
+	keys []iniKey
+}
+
+// iniKey is a key of iniSection.
+type iniKey struct {
+	name  string
+	value string
+}
+
+// NewCmdInit returns the init command.
+func NewCmdInit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Set up a profile interactively",
+		Long: `Set up a profile interactively.
+
+awsmfa asks for the profile name, action mode, long term access keys, MFA device's serial, role arn and regions,
+and then merges the sections into the shared credentials file, the shared config file and awsmfa's configuration file.
+Existing sections are never overwritten without your confirmation, and the diff is shown before writing each file.`,
+		Args: cobra.NoArgs,
+		RunE: runInitCmd,
+	}
+
+	return cmd
+}
+
+func runInitCmd(cmd *cobra.Command, args []string) error {
+	reader := bufio.NewReader(os.Stdin)
+
+	answers, err := askInitAnswers(reader, os.Stdout, func() (string, error) {
+		return readSecret(reader)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete the wizard: %w", err)
+	}
+
+	targets := []initTarget{
+		{path: credentialsFilePath, perm: 0600, sections: buildInitCredentialsSections(answers)},
+		{path: configFilePath, perm: 0600, sections: buildInitConfigSections(answers)},
+	}
+	if answers.setAsDefault {
+		targets = append(targets, initTarget{path: awsmfaCfgFilePath, perm: 0644, sections: buildInitAwsmfaConfigSections(answers)})
+	}
+
+	for _, t := range targets {
+		before, err := os.ReadFile(t.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read %v: %w", t.path, err)
+		}
+		f, err := ini.Load(before)
+		if err != nil {
+			return fmt.Errorf("failed to load %v: %w", t.path, err)
+		}
+
+		if err := mergeINISections(f, t.sections, func(name string) (bool, error) {
+			return askYesNo(reader, os.Stdout, fmt.Sprintf("The section [%v] already exists in %v. Overwrite its keys?", name, t.path))
+		}); err != nil {
+			return fmt.Errorf("failed to merge sections into %v: %w", t.path, err)
+		}
+
+		var after bytes.Buffer
+		if _, err := f.WriteTo(&after); err != nil {
+			return fmt.Errorf("failed to render %v: %w", t.path, err)
+		}
+		if string(before) == after.String() {
+			printCyan(fmt.Sprintf("No change in %v\n", t.path))
+			continue
+		}
+
+		fmt.Printf("\n--- %v\n+++ %v\n", t.path, t.path)
+		printDiff(diffLines(maskSecrets(string(before)), maskSecrets(after.String())))
+
+		ok, err := askYesNo(reader, os.Stdout, fmt.Sprintf("Write these changes to %v?", t.path))
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		if !ok {
+			printBlue(fmt.Sprintf("Skipped %v\n", t.path))
+			continue
+		}
+
+		if err := writeFileWithDir(t.path, after.Bytes(), t.perm); err != nil {
+			return fmt.Errorf("failed to write %v: %w", t.path, err)
+		}
+		printCyan(fmt.Sprintf("Successfully updated %v\n", t.path))
+	}
+
+	printCyan(fmt.Sprintf("Done! You can get temporary credentials by 'awsmfa --profile %v'\n", answers.profile))
+	return nil
+}
+
+// askInitAnswers asks all questions of the init wizard.
+func askInitAnswers(r *bufio.Reader, w io.Writer, readSecret func() (string, error)) (initAnswers, error) {
+	var a initAnswers
+	var err error
+
+	if a.profile, err = askValue(r, w, "Profile name", defaultProfile, validateNotEmpty); err != nil {
+		return a, err
+	}
+	if a.mode, err = askValue(r, w, "Action mode (get-session-token or assume-role)", defaultMode, validateMode); err != nil {
+		return a, err
+	}
+	if a.accessKeyID, err = askValue(r, w, "AWS access key ID", "", validateNotEmpty); err != nil {
+		return a, err
+	}
+	for a.secretAccessKey == "" {
+		fmt.Fprint(w, "AWS secret access key (hidden): ")
+		if a.secretAccessKey, err = readSecret(); err != nil {
+			return a, err
+		}
+	}
+	if a.mfaSerial, err = askValue(r, w, "MFA device's serial (such as arn:aws:iam::123456789012:mfa/user)", "", validateNotEmpty); err != nil {
+		return a, err
+	}
+	if a.mode == "assume-role" {
+		if a.roleArn, err = askValue(r, w, "ARN of the IAM role to assume (such as arn:aws:iam::123456789012:role/role-name)", "", validateNotEmpty); err != nil {
+			return a, err
+		}
+	}
+	if a.endpointRegion, err = askValue(r, w, "Region to call AWS STS", initDefaultEndpointRegion, validateNotEmpty); err != nil {
+		return a, err
+	}
+	if a.region, err = askValue(r, w, "Region to use after MFA", initDefaultRegion, validateNotEmpty); err != nil {
+		return a, err
+	}
+	if a.setAsDefault, err = askYesNo(r, w, fmt.Sprintf("Set \"%v\" as awsmfa's default profile?", a.profile)); err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// askValue asks a question until a valid answer is given. An empty answer means the default value.
+func askValue(r *bufio.Reader, w io.Writer, question string, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(w, "%v [%v]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(w, "%v: ", question)
+		}
+
+		v, err := readLine(r)
+		if err != nil {
+			return "ERROR", err
+		}
+		if v == "" {
+			v = defaultValue
+		}
+		if err := validate(v); err != nil {
+			fmt.Fprintf(w, "Invalid value: %v\n", err)
+			continue
+		}
+		return v, nil
+	}
+}
+
+// askYesNo asks a yes/no question. The default answer is no.
+func askYesNo(r *bufio.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%v [y/N]: ", question)
+	v, err := readLine(r)
+	if err != nil {
+		return false, err
+	}
+	v = strings.ToLower(v)
+	return v == "y" || v == "yes", nil
+}
+
+// readLine reads a line and trims spaces.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "ERROR", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// readSecret reads a line without echo if stdin is a terminal.
+func readSecret(r *bufio.Reader) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(r)
+	}
+	b, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "ERROR", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// buildInitCredentialsSections returns the sections to be merged into the shared credentials file.
+func buildInitCredentialsSections(a initAnswers) []iniSection {
+	return []iniSection{
+		{name: a.profile + beforeMFASuffix, keys: []iniKey{
+			{name: "aws_access_key_id", value: a.accessKeyID},
+			{name: "aws_secret_access_key", value: a.secretAccessKey},
+		}},
+	}
+}
+
+// buildInitConfigSections returns the sections to be merged into the shared config file.
+func buildInitConfigSections(a initAnswers) []iniSection {
+	beforeMFA := iniSection{name: "profile " + a.profile + beforeMFASuffix, keys: []iniKey{
+		{name: "region", value: a.endpointRegion},
+		{name: "output", value: "json"},
+		{name: "mfa_serial", value: a.mfaSerial},
+	}}
+	if a.mode == "assume-role" {
+		beforeMFA.keys = append(beforeMFA.keys, iniKey{name: "awsmfa_role_arn", value: a.roleArn})
+	}
+
+	return []iniSection{
+		beforeMFA,
+		{name: "profile " + a.profile, keys: []iniKey{
+			{name: "region", value: a.region},
+			{name: "output", value: "json"},
+		}},
+	}
+}
+
+// buildInitAwsmfaConfigSections returns the sections to be merged into awsmfa's configuration file.
+func buildInitAwsmfaConfigSections(a initAnswers) []iniSection {
+	return []iniSection{
+		{name: "default-value", keys: []iniKey{
+			{name: "profile", value: a.profile},
+		}},
+	}
+}
+
+// mergeINISections sets keys of given sections into the file.
+// If a section already exists, its keys are set only when overwrite returns true.
+func mergeINISections(f *ini.File, sections []iniSection, overwrite func(name string) (bool, error)) error {
+	for _, s := range sections {
+		if _, err := f.GetSection(s.name); err == nil {
+			ok, err := overwrite(s.name)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		for _, k := range s.keys {
+			f.Section(s.name).Key(k.name).SetValue(k.value)
+		}
+	}
+	return nil
+}
+
+// maskSecrets hides values of aws_secret_access_key and aws_session_token except the last 4 characters like aws configure.
+func maskSecrets(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if k := strings.TrimSpace(kv[0]); k != "aws_secret_access_key" && k != "aws_session_token" {
+			continue
+		}
+		v := strings.TrimSpace(kv[1])
+		if len(v) > 4 {
+			v = v[len(v)-4:]
+		}
+		lines[i] = kv[0] + "= ****************" + v
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffLines returns a line based diff between before and after.
+// Each line is prefixed by "-" (removed), "+" (added) or " " (unchanged).
+// Unchanged lines are included only if they are section headers, to show where the changes are.
+func diffLines(before string, after string) []string {
+	a := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(after, "\n"), "\n")
+	if before == "" {
+		a = []string{}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			if strings.HasPrefix(strings.TrimSpace(a[i]), "[") {
+				diff = append(diff, " "+a[i])
+			}
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	return diff
+}
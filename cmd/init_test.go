@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+func Test_askInitAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		secret  string
+		want    initAnswers
+		wantErr bool
+	}{
+		{
+			name:   "S01: get-session-token with default values",
+			input:  "\n\nAKIAEXAMPLE\narn:aws:iam::123456789012:mfa/user\n\n\n\n",
+			secret: "SECRET",
+			want:   initAnswers{profile: "default", mode: "get-session-token", accessKeyID: "AKIAEXAMPLE", secretAccessKey: "SECRET", mfaSerial: "arn:aws:iam::123456789012:mfa/user", endpointRegion: "us-east-1", region: "us-east-1", setAsDefault: false},
+		},
+		{
+			name:   "S02: assume-role after an invalid mode",
+			input:  "dev\nwrong-mode💀\nassume-role\nAKIAEXAMPLE\narn:aws:iam::123456789012:mfa/user\narn:aws:iam::123456789012:role/admin\nap-northeast-1\nap-northeast-1\ny\n",
+			secret: "SECRET",
+			want:   initAnswers{profile: "dev", mode: "assume-role", accessKeyID: "AKIAEXAMPLE", secretAccessKey: "SECRET", mfaSerial: "arn:aws:iam::123456789012:mfa/user", roleArn: "arn:aws:iam::123456789012:role/admin", endpointRegion: "ap-northeast-1", region: "ap-northeast-1", setAsDefault: true},
+		},
+		{
+			name:    "F01: input ends",
+			input:   "dev\n",
+			secret:  "SECRET",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			got, err := askInitAnswers(r, ioutil.Discard, func() (string, error) { return tt.secret, nil })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("askInitAnswers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("askInitAnswers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeINISections(t *testing.T) {
+	sections := []iniSection{
+		{name: "existing", keys: []iniKey{{name: "region", value: "new-region"}}},
+		{name: "new", keys: []iniKey{{name: "region", value: "new-region"}}},
+	}
+
+	tests := []struct {
+		name           string
+		overwrite      bool
+		wantExisting   string
+		wantNew        string
+		wantKeptSecret string
+	}{
+		{name: "S01: overwrite", overwrite: true, wantExisting: "new-region", wantNew: "new-region", wantKeptSecret: "SECRET"},
+		{name: "S02: keep", overwrite: false, wantExisting: "old-region", wantNew: "new-region", wantKeptSecret: "SECRET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ini.Load([]byte("[existing]\nregion = old-region\naws_secret_access_key = SECRET\n"))
+			if err != nil {
+				t.Fatalf("failed to load test data: %v", err)
+			}
+
+			if err := mergeINISections(f, sections, func(string) (bool, error) { return tt.overwrite, nil }); err != nil {
+				t.Errorf("mergeINISections() error = %v", err)
+			}
+			if got := f.Section("existing").Key("region").String(); got != tt.wantExisting {
+				t.Errorf("mergeINISections() existing = %v, want %v", got, tt.wantExisting)
+			}
+			if got := f.Section("new").Key("region").String(); got != tt.wantNew {
+				t.Errorf("mergeINISections() new = %v, want %v", got, tt.wantNew)
+			}
+			if got := f.Section("existing").Key("aws_secret_access_key").String(); got != tt.wantKeptSecret {
+				t.Errorf("mergeINISections() secret = %v, want %v", got, tt.wantKeptSecret)
+			}
+		})
+	}
+}
+
+func Test_diffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{name: "S01: new file", before: "", after: "[a]\nk = v\n", want: []string{"+[a]", "+k = v"}},
+		{name: "S02: changed key", before: "[a]\nk = v\nx = y\n", after: "[a]\nk = w\nx = y\n", want: []string{" [a]", "-k = v", "+k = w"}},
+		{name: "S03: no change", before: "[a]\nk = v\n", after: "[a]\nk = v\n", want: []string{" [a]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_maskSecrets(t *testing.T) {
+	content := "[a]\naws_access_key_id = AKIA\naws_secret_access_key = MYSECRET\naws_session_token=TOK\n"
+	want := "[a]\naws_access_key_id = AKIA\naws_secret_access_key = ****************CRET\naws_session_token= ****************TOK\n"
+	if got := maskSecrets(content); got != want {
+		t.Errorf("maskSecrets() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockDirName is the directory in the directory of awsmfa's configuration file which holds a lock file per profile.
+// While a process asks the MFA token code and saves temporary credentials of a profile, the others wait for it.
+const lockDirName = "locks"
+
+// lockWriteTimeout is how long a lock file without a process ID is regarded as alive.
+// The holder writes its process ID just after creating the lock file, so such a lock file is left by a process which died in between.
+// A lock file with a process ID is never stolen by its age, because the holder may wait for the MFA token code without a timeout.
+const lockWriteTimeout = time.Minute
+
+// lockPollInterval is the interval to check if the lock is released.
+const lockPollInterval = 500 * time.Millisecond
+
+var unsafeLockNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// profileLock is an exclusive lock of a profile among awsmfa processes.
+type profileLock struct {
+	path string
+}
+
+// lockFilePath returns the path of the lock file of the profile.
+func lockFilePath(profile string) string {
+	return filepath.Join(awsmfaCfgFileDir, lockDirName, unsafeLockNameChars.ReplaceAllString(profile, "_")+".lock")
+}
+
+// acquireProfileLock acquires the lock file. If another process holds it, acquireProfileLock calls onWait once and waits for it until ctx is done.
+// waited is true if another process held the lock, so that the caller can check if the process has already done the work.
+func acquireProfileLock(ctx context.Context, path string, onWait func(holderPID int)) (lock *profileLock, waited bool, err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, false, fmt.Errorf("failed to create directory %v: %w", filepath.Dir(path), err)
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, waited, fmt.Errorf("failed to write lock file %v: %w", path, err)
+			}
+			return &profileLock{path: path}, waited, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, waited, fmt.Errorf("failed to create lock file %v: %w", path, err)
+		}
+
+		pid, stale := inspectLockFile(path, time.Now())
+		if stale {
+			if err := stealStaleLock(path, pid); err != nil {
+				return nil, waited, err
+			}
+			continue
+		}
+		if !waited && onWait != nil {
+			onWait(pid)
+		}
+		waited = true
+		select {
+		case <-ctx.Done():
+			return nil, waited, fmt.Errorf("canceled while waiting for another awsmfa process: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// release releases the lock.
+func (l *profileLock) release() error {
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file %v: %w", l.path, err)
+	}
+	return nil
+}
+
+// inspectLockFile returns the process ID of the holder of the lock, and whether the lock is left by a dead process.
+// The process ID is 0 if the lock file doesn't have it.
+func inspectLockFile(path string, now time.Time) (pid int, stale bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		// The lock has just been released.
+		return 0, false
+	}
+	pid, err = readLockPID(path)
+	if err != nil {
+		// The holder may be writing its process ID.
+		return 0, now.Sub(info.ModTime()) > lockWriteTimeout
+	}
+	return pid, !isProcessAlive(pid)
+}
+
+// readLockPID returns the process ID written in the lock file.
+func readLockPID(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// stealStaleLock moves the stale lock file of stalePID away, so that the next exclusive create acquires the lock.
+// Removing the file could remove a new lock file which another process has created after stealing the stale one,
+// so the file is renamed to a unique name, and put back if it turns out not to be the stale one.
+func stealStaleLock(path string, stalePID int) error {
+	moved := fmt.Sprintf("%v.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, moved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Another process has stolen or released it.
+			return nil
+		}
+		return fmt.Errorf("failed to move stale lock file %v: %w", path, err)
+	}
+	defer os.Remove(moved)
+
+	if pid, _ := readLockPID(moved); pid != stalePID {
+		// It is the lock file of a live holder. Linking fails only if yet another process has created a lock file.
+		if err := os.Link(moved, path); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to restore lock file %v: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// isProcessAlive reports whether the process is running.
+// On Windows, os.FindProcess opens the process and fails if it doesn't exist, while a signal cannot check it.
+// A process of another user, which refuses the signal, is alive.
+func isProcessAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		p.Release()
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_acquireProfileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockDirName, "sample.lock")
+
+	lock, waited, err := acquireProfileLock(context.Background(), path, nil)
+	if err != nil || waited {
+		t.Fatalf("acquireProfileLock() = %v, %v, want no wait", waited, err)
+	}
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		close(released)
+		lock.release()
+	}()
+
+	var gotPID int
+	second, waited, err := acquireProfileLock(context.Background(), path, func(holderPID int) { gotPID = holderPID })
+	if err != nil {
+		t.Fatalf("acquireProfileLock() error = %v", err)
+	}
+	defer second.release()
+	select {
+	case <-released:
+	default:
+		t.Errorf("acquireProfileLock() acquired the lock before it was released")
+	}
+	if !waited || gotPID != os.Getpid() {
+		t.Errorf("acquireProfileLock() waited = %v, holder = %v, want true, %v", waited, gotPID, os.Getpid())
+	}
+}
+
+func Test_acquireProfileLock_canceled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.lock")
+	lock, _, err := acquireProfileLock(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("acquireProfileLock() error = %v", err)
+	}
+	defer lock.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+	defer cancel()
+	if _, _, err := acquireProfileLock(ctx, path, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquireProfileLock() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func Test_acquireProfileLock_stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.lock")
+	// The lock file left by a dead process.
+	if err := os.WriteFile(path, []byte("2147483647\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	lock, waited, err := acquireProfileLock(context.Background(), path, nil)
+	if err != nil || waited {
+		t.Fatalf("acquireProfileLock() = %v, %v, want no wait", waited, err)
+	}
+	lock.release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("profileLock.release() left the lock file: %v", err)
+	}
+}
+
+func Test_inspectLockFile(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		content   string
+		age       time.Duration
+		wantPID   int
+		wantStale bool
+	}{
+		{name: "S01: dead holder", content: "2147483647\n", age: 0, wantPID: 2147483647, wantStale: true},
+		{name: "S02: live holder", content: strconv.Itoa(os.Getpid()) + "\n", age: 0, wantPID: os.Getpid(), wantStale: false},
+		{name: "S03: live holder waiting for a long time", content: strconv.Itoa(os.Getpid()) + "\n", age: 24 * time.Hour, wantPID: os.Getpid(), wantStale: false},
+		{name: "S04: holder writing its process ID", content: "", age: 0, wantPID: 0, wantStale: false},
+		{name: "S05: holder died before writing its process ID", content: "", age: 2 * lockWriteTimeout, wantPID: 0, wantStale: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sample.lock")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to create test data: %v", err)
+			}
+			modTime := now.Add(-tt.age)
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatalf("failed to create test data: %v", err)
+			}
+			pid, stale := inspectLockFile(path, now)
+			if pid != tt.wantPID || stale != tt.wantStale {
+				t.Errorf("inspectLockFile() = %v, %v, want %v, %v", pid, stale, tt.wantPID, tt.wantStale)
+			}
+		})
+	}
+}
+
+func Test_stealStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sample.lock")
+	// Another process has stolen the stale lock and created its own lock file since it was inspected.
+	live := strconv.Itoa(os.Getpid()) + "\n"
+	if err := os.WriteFile(path, []byte(live), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	if err := stealStaleLock(path, 2147483647); err != nil {
+		t.Fatalf("stealStaleLock() error = %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != live {
+		t.Errorf("stealStaleLock() removed the lock file of the live holder: %q, %v", got, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("stealStaleLock() left files: %v", entries)
+	}
+
+	// The stale lock file is moved away.
+	if err := os.WriteFile(path, []byte("2147483647\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	if err := stealStaleLock(path, 2147483647); err != nil {
+		t.Fatalf("stealStaleLock() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("stealStaleLock() left files: %v", entries)
+	}
+}
+
+func Test_lockFilePath(t *testing.T) {
+	got := filepath.Base(lockFilePath("dev/admin:1"))
+	if got != "dev_admin_1.lock" {
+		t.Errorf("lockFilePath() = %v, want dev_admin_1.lock", got)
+	}
+	if !strings.HasPrefix(lockFilePath("dev"), awsmfaCfgFileDir) {
+		t.Errorf("lockFilePath() = %v, want under %v", lockFilePath("dev"), awsmfaCfgFileDir)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// metadataKeyPrefix is a prefix of keys of session metadata saved with temporary credentials.
+const metadataKeyPrefix = "awsmfa_session_"
+
+// sessionMetadata describes how temporary credentials were issued.
+type sessionMetadata struct {
+	sourceProfile      string
+	mode               string
+	roleArn            string
+	roleSessionName    string
+	mfaSerial          string
+	issuedAt           time.Time
+	endpointRegion     string
+	assumedRoleUserArn string
+	packedPolicySize   *int32
+}
+
+// keys returns the keys to be saved in the profile. Empty values are omitted.
+func (m sessionMetadata) keys() []iniKey {
+	keys := []iniKey{
+		{name: metadataKeyPrefix + "source_profile", value: m.sourceProfile},
+		{name: metadataKeyPrefix + "mode", value: m.mode},
+		{name: metadataKeyPrefix + "role_arn", value: m.roleArn},
+		{name: metadataKeyPrefix + "role_session_name", value: m.roleSessionName},
+		{name: metadataKeyPrefix + "mfa_serial", value: m.mfaSerial},
+		{name: metadataKeyPrefix + "issued_at", value: m.issuedAt.UTC().Format(time.RFC3339)},
+		{name: metadataKeyPrefix + "endpoint_region", value: m.endpointRegion},
+		{name: metadataKeyPrefix + "assumed_role_user_arn", value: m.assumedRoleUserArn},
+	}
+	if m.packedPolicySize != nil {
+		keys = append(keys, iniKey{name: metadataKeyPrefix + "packed_policy_size", value: strconv.Itoa(int(*m.packedPolicySize))})
+	}
+
+	nonEmpty := []iniKey{}
+	for _, k := range keys {
+		if k.value != "" {
+			nonEmpty = append(nonEmpty, k)
+		}
+	}
+	return nonEmpty
+}
+
+// writeMetadata replaces session metadata in the section. If metadata is nil, writeMetadata only removes stale metadata.
+func writeMetadata(sec *ini.Section, metadata *sessionMetadata) {
+	for _, k := range sec.KeyStrings() {
+		if strings.HasPrefix(k, metadataKeyPrefix) {
+			sec.DeleteKey(k)
+		}
+	}
+	if metadata == nil {
+		return
+	}
+	for _, k := range metadata.keys() {
+		sec.Key(k.name).SetValue(k.value)
+	}
+}
+
+// readMetadata returns session metadata saved in the section. The keys are without metadataKeyPrefix.
+func readMetadata(sec *ini.Section) map[string]string {
+	metadata := map[string]string{}
+	for _, k := range sec.Keys() {
+		if strings.HasPrefix(k.Name(), metadataKeyPrefix) {
+			metadata[strings.TrimPrefix(k.Name(), metadataKeyPrefix)] = k.String()
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+func Test_sessionMetadata_keys(t *testing.T) {
+	size := int32(6)
+	m := sessionMetadata{
+		sourceProfile:    "sample-before-mfa",
+		mode:             "assume-role",
+		roleArn:          "arn:aws:iam::123456789012:role/admin",
+		mfaSerial:        "arn:aws:iam::123456789012:mfa/user",
+		issuedAt:         time.Date(2999, 11, 23, 14, 15, 16, 0, time.UTC),
+		packedPolicySize: &size,
+	}
+	want := map[string]string{
+		"awsmfa_session_source_profile":     "sample-before-mfa",
+		"awsmfa_session_mode":               "assume-role",
+		"awsmfa_session_role_arn":           "arn:aws:iam::123456789012:role/admin",
+		"awsmfa_session_mfa_serial":         "arn:aws:iam::123456789012:mfa/user",
+		"awsmfa_session_issued_at":          "2999-11-23T14:15:16Z",
+		"awsmfa_session_packed_policy_size": "6",
+	}
+
+	got := m.keys()
+	if len(got) != len(want) {
+		t.Errorf("sessionMetadata.keys() = %+v, want %+v", got, want)
+	}
+	for _, k := range got {
+		if want[k.name] != k.value {
+			t.Errorf("sessionMetadata.keys() %v = %v, want %v", k.name, k.value, want[k.name])
+		}
+	}
+}
+
+func Test_writeMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *sessionMetadata
+		wantKeys []string
+	}{
+		{name: "S01: replace", metadata: &sessionMetadata{mode: "get-session-token", issuedAt: time.Now()}, wantKeys: []string{"aws_access_key_id", "awsmfa_session_mode", "awsmfa_session_issued_at"}},
+		{name: "S02: remove stale metadata", metadata: nil, wantKeys: []string{"aws_access_key_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ini.Load([]byte("[sample]\naws_access_key_id = XXXX\nawsmfa_session_role_arn = stale\n"))
+			if err != nil {
+				t.Fatalf("failed to load test data: %v", err)
+			}
+
+			writeMetadata(f.Section("sample"), tt.metadata)
+
+			got := f.Section("sample").KeyStrings()
+			if len(got) != len(tt.wantKeys) {
+				t.Errorf("writeMetadata() keys = %v, want %v", got, tt.wantKeys)
+			}
+			for i := range got {
+				if i < len(tt.wantKeys) && got[i] != tt.wantKeys[i] {
+					t.Errorf("writeMetadata() keys = %v, want %v", got, tt.wantKeys)
+				}
+			}
+		})
+	}
+}
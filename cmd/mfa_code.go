@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxMFACodeAttempts is the number of MFA token codes which awsmfa sends to AWS STS in a run.
+// Too many failures of MFA may lock the IAM user, so the retry is bounded.
+const maxMFACodeAttempts = 3
+
+// validateMFACode checks if the MFA token code is 6 digits.
+func validateMFACode(code string) error {
+	if len(code) != 6 {
+		return fmt.Errorf("the MFA token code should be 6 digits")
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("the MFA token code should be 6 digits")
+		}
+	}
+	return nil
+}
+
+// askMFACode asks the MFA token code until 6 digits which are not used recently with the MFA device are given.
+func askMFACode(readCode func() (string, error), w io.Writer, question string, statePath string, mfaSerial string) (string, error) {
+	for {
+		fmt.Fprintf(w, "%v: ", question)
+		code, err := readCode()
+		if err != nil {
+			return "ERROR", err
+		}
+		if err := validateMFACode(code); err != nil {
+			fmt.Fprintf(w, "Invalid value: %v\n", err)
+			continue
+		}
+		if err := checkMFACodeReuse(statePath, mfaSerial, code, time.Now()); err != nil {
+			fmt.Fprintf(w, "Invalid value: %v\n", err)
+			continue
+		}
+		return code, nil
+	}
+}
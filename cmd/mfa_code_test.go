@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+func Test_validateMFACode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{name: "S01: 6 digits", code: "012345", wantErr: false},
+		{name: "F01: 5 digits", code: "12345", wantErr: true},
+		{name: "F02: 7 digits", code: "1234567", wantErr: true},
+		{name: "F03: not digits", code: "12345a", wantErr: true},
+		{name: "F04: empty", code: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMFACode(tt.code); (err != nil) != tt.wantErr {
+				t.Errorf("validateMFACode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// linesOf returns a function which reads the input line by line like terminal.readSecret.
+func linesOf(input string) func() (string, error) {
+	r := bufio.NewReader(strings.NewReader(input))
+	return func() (string, error) { return readLine(r) }
+}
+
+func Test_askMFACode(t *testing.T) {
+	var w bytes.Buffer
+	got, err := askMFACode(linesOf("1234\n 654321 \n"), &w, "Input your MFA token code", filepath.Join(t.TempDir(), stateFileName), "serial")
+	if err != nil {
+		t.Fatalf("askMFACode() error = %v", err)
+	}
+	if got != "654321" {
+		t.Errorf("askMFACode() = %v, want 654321", got)
+	}
+	if !strings.Contains(w.String(), "6 digits") {
+		t.Errorf("askMFACode() output = %v, want a message about 6 digits", w.String())
+	}
+
+	if _, err := askMFACode(linesOf(""), &w, "Input your MFA token code", filepath.Join(t.TempDir(), stateFileName), "serial"); err == nil {
+		t.Errorf("askMFACode() error = nil, want error on EOF")
+	}
+
+	canceled := func() (string, error) { return "ERROR", errInputCanceled }
+	if _, err := askMFACode(canceled, &w, "Input your MFA token code", filepath.Join(t.TempDir(), stateFileName), "serial"); !errors.Is(err, errInputCanceled) {
+		t.Errorf("askMFACode() error = %v, want %v", err, errInputCanceled)
+	}
+}
+
+func Test_isInvalidMFACodeError(t *testing.T) {
+	if !isInvalidMFACodeError(&smithy.GenericAPIError{Code: "AccessDenied", Message: "MultiFactorAuthentication failed with invalid MFA one time pass code."}) {
+		t.Errorf("isInvalidMFACodeError() = false, want true")
+	}
+	if isInvalidMFACodeError(&smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}) {
+		t.Errorf("isInvalidMFACodeError() = true, want false")
+	}
+}
+
+func Test_askMFACode_reused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), stateFileName)
+	if err := recordMFACode(path, "serial", "123456", time.Now()); err != nil {
+		t.Fatalf("recordMFACode() error = %v", err)
+	}
+
+	var w bytes.Buffer
+	got, err := askMFACode(linesOf("123456\n654321\n"), &w, "Input your MFA token code", path, "serial")
+	if err != nil {
+		t.Fatalf("askMFACode() error = %v", err)
+	}
+	if got != "654321" {
+		t.Errorf("askMFACode() = %v, want 654321", got)
+	}
+	if !strings.Contains(w.String(), "already used") {
+		t.Errorf("askMFACode() output = %v, want a warning about the reused code", w.String())
+	}
+}
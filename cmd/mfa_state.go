@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// stateFileName is the file in the directory of awsmfa's configuration file which records the last used MFA token code per MFA device.
+// Only the hash of the code is recorded.
+//
+// [arn:aws:iam::123456789012:mfa/user]
+// last_code_hash = 0123abcd...
+// last_used_at   = 2026-10-16T10:00:00Z
+const stateFileName = "state"
+
+// mfaCodeReuseWindow is how long a used MFA token code is regarded as reused.
+// AWS accepts a TOTP code in a few periods of 30 seconds around the current one and rejects the code used once in them.
+const mfaCodeReuseWindow = 3 * totpPeriod
+
+// totpPeriod is the period of TOTP codes of virtual MFA devices.
+const totpPeriod = 30 * time.Second
+
+// stateFilePath returns the path of the file which records the last used MFA token code.
+func stateFilePath() string {
+	return awsmfaCfgFileDir + "/" + stateFileName
+}
+
+// hashMFACode returns the hash of the MFA token code. The serial is mixed so that the same code of another device has another hash.
+func hashMFACode(mfaSerial string, code string) string {
+	sum := sha256.Sum256([]byte(mfaSerial + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordMFACode records the MFA token code used with the MFA device.
+func recordMFACode(path string, mfaSerial string, code string, usedAt time.Time) error {
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", path, err)
+	}
+	sec := f.Section(mfaSerial)
+	sec.Key("last_code_hash").SetValue(hashMFACode(mfaSerial, code))
+	sec.Key("last_used_at").SetValue(usedAt.UTC().Format(time.RFC3339))
+
+	return saveIniAtomicWithDir(f, path, 0600)
+}
+
+// checkMFACodeReuse returns an error if the MFA token code was already used with the MFA device in mfaCodeReuseWindow.
+func checkMFACodeReuse(path string, mfaSerial string, code string, now time.Time) error {
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return nil
+	}
+	sec, err := f.GetSection(mfaSerial)
+	if err != nil || sec.Key("last_code_hash").String() != hashMFACode(mfaSerial, code) {
+		return nil
+	}
+	usedAt, err := sec.Key("last_used_at").TimeFormat(time.RFC3339)
+	if err != nil || now.Sub(usedAt) >= mfaCodeReuseWindow {
+		return nil
+	}
+	next := totpPeriod - now.Sub(now.Truncate(totpPeriod))
+	return fmt.Errorf("the MFA token code was already used at %v. AWS rejects it, so please wait for the next code (in %v)", usedAt.Local().Format("15:04:05"), next.Round(time.Second))
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_checkMFACodeReuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "awsmfa", stateFileName)
+	usedAt := time.Date(2026, 10, 16, 10, 0, 5, 0, time.UTC)
+
+	if err := checkMFACodeReuse(path, "serial", "123456", usedAt); err != nil {
+		t.Errorf("checkMFACodeReuse() error = %v, want nil for missing file", err)
+	}
+	if err := recordMFACode(path, "serial", "123456", usedAt); err != nil {
+		t.Fatalf("recordMFACode() error = %v", err)
+	}
+	if err := recordMFACode(path, "other", "111111", usedAt); err != nil {
+		t.Fatalf("recordMFACode() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		serial  string
+		code    string
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "S01: another code", serial: "serial", code: "654321", now: usedAt.Add(10 * time.Second), wantErr: false},
+		{name: "S02: same code of another device", serial: "other", code: "123456", now: usedAt.Add(10 * time.Second), wantErr: false},
+		{name: "S03: same code after the window", serial: "serial", code: "123456", now: usedAt.Add(mfaCodeReuseWindow), wantErr: false},
+		{name: "F01: same code in the window", serial: "serial", code: "123456", now: usedAt.Add(10 * time.Second), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkMFACodeReuse(path, tt.serial, tt.code, tt.now); (err != nil) != tt.wantErr {
+				t.Errorf("checkMFACodeReuse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
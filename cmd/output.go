@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of --output.
+var outputFormats = []string{"table", "json", "yaml", "none"}
+
+// Outcomes of a run of awsmfa.
+const (
+	outcomeRefreshed = "refreshed" // new temporary credentials are saved.
+	outcomeActive    = "active"    // the temporary credentials are still active, so nothing is done.
+	outcomeShared    = "shared"    // another awsmfa process has refreshed the temporary credentials.
+	outcomeExpired   = "expired"   // the temporary credentials have expired. Only 'awsmfa prompt' reports it.
+	outcomeFailed    = "failed"
+)
+
+// runResult is the machine readable result of a run of awsmfa, printed by --output json or yaml, and the data of --format.
+//
+//	.Profile    the profile specified by --profile
+//	.Mode       get-session-token or assume-role
+//	.Params     the resolved request params keyed by the name, such as duration_seconds. Each has .Value and .Source
+//	.Expiration the expiration of the temporary credentials
+//	.Metadata   the session metadata saved by --save-metadata keyed by the name without awsmfa_session_, such as issued_at
+//	.Outcome    refreshed, active, shared, expired or failed
+//	.Error      the error message if failed
+type runResult struct {
+	Profile    string                 `json:"profile" yaml:"profile"`
+	Mode       string                 `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Params     map[string]resultParam `json:"params" yaml:"params"`
+	Expiration *time.Time             `json:"expiration,omitempty" yaml:"expiration,omitempty"`
+	Metadata   map[string]string      `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Outcome    string                 `json:"outcome" yaml:"outcome"`
+	Error      string                 `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// resultParam is a resolved request param and the source of it.
+type resultParam struct {
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// templateFuncs are the functions available in --format.
+var templateFuncs = template.FuncMap{
+	// remaining returns the remaining time until the expiration such as 2h13m, or EXPIRED.
+	"remaining": func(t *time.Time) string {
+		if t == nil || !time.Now().Before(*t) {
+			return "EXPIRED"
+		}
+		return humanDuration(time.Until(*t))
+	},
+	// rfc3339 formats the time in RFC3339 such as 2999-11-23T14:15:16Z.
+	"rfc3339": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+}
+
+// parseFormat parses the template of --format. A newline is appended if the template doesn't end with it.
+func parseFormat(format string) (*template.Template, error) {
+	if len(format) == 0 || format[len(format)-1] != '\n' {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Funcs(templateFuncs).Option("missingkey=zero").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format: %w", err)
+	}
+	return tmpl, nil
+}
+
+func newRunResult() *runResult {
+	return &runResult{Params: map[string]resultParam{}}
+}
+
+// setParam records a resolved request param.
+func (r *runResult) setParam(name string, value string, source string) {
+	r.Params[name] = resultParam{Value: value, Source: source}
+}
+
+// loadMetadata records the session metadata saved in the output profile.
+func (r *runResult) loadMetadata() {
+	output, err := ini.LooseLoad(r.Params["output_credentials_file"].Value)
+	if err != nil {
+		return
+	}
+	if sec, err := output.GetSection(r.Params["output_profile"].Value); err == nil {
+		if metadata := readMetadata(sec); len(metadata) > 0 {
+			r.Metadata = metadata
+		}
+	}
+}
+
+// finish records the outcome of the run. A failure overrides the outcome.
+func (r *runResult) finish(err error) {
+	if err != nil {
+		r.Outcome = outcomeFailed
+		r.Error = err.Error()
+	}
+}
+
+// validateOutputFormat checks if the format is one of outputFormats.
+func validateOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format: %v. Please use one of %v", format, outputFormats)
+}
+
+// printRunResult prints the result in the format. table and none print nothing, because the result has been already shown to humans.
+func printRunResult(w io.Writer, format string, r *runResult) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return nil
+}
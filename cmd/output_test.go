@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_validateOutputFormat(t *testing.T) {
+	for _, f := range []string{"table", "json", "yaml", "none"} {
+		if err := validateOutputFormat(f); err != nil {
+			t.Errorf("validateOutputFormat(%v) error = %v", f, err)
+		}
+	}
+	if err := validateOutputFormat("xml"); err == nil {
+		t.Errorf("validateOutputFormat(xml) error = nil, want error")
+	}
+}
+
+func Test_printRunResult(t *testing.T) {
+	expiration := time.Date(2999, 11, 23, 14, 15, 16, 0, time.UTC)
+	r := newRunResult()
+	r.Profile = "sample"
+	r.Mode = "assume-role"
+	r.setParam("duration_seconds", "3600", CliOpt.String())
+	r.Outcome, r.Expiration = outcomeRefreshed, &expiration
+
+	var buf bytes.Buffer
+	if err := printRunResult(&buf, "json", r); err != nil {
+		t.Fatalf("printRunResult() error = %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printRunResult() printed invalid json: %v", err)
+	}
+	if got["outcome"] != "refreshed" || got["expiration"] != "2999-11-23T14:15:16Z" || got["mode"] != "assume-role" {
+		t.Errorf("printRunResult() = %v", buf.String())
+	}
+	if p := got["params"].(map[string]interface{})["duration_seconds"].(map[string]interface{}); p["value"] != "3600" || p["source"] != "cli option" {
+		t.Errorf("printRunResult() params = %v", p)
+	}
+
+	buf.Reset()
+	if err := printRunResult(&buf, "yaml", r); err != nil {
+		t.Fatalf("printRunResult() error = %v", err)
+	}
+	var gotYAML runResult
+	if err := yaml.Unmarshal(buf.Bytes(), &gotYAML); err != nil {
+		t.Fatalf("printRunResult() printed invalid yaml: %v", err)
+	}
+	if gotYAML.Outcome != outcomeRefreshed || gotYAML.Params["duration_seconds"].Value != "3600" {
+		t.Errorf("printRunResult() = %v", buf.String())
+	}
+
+	for _, f := range []string{"table", "none"} {
+		buf.Reset()
+		if err := printRunResult(&buf, f, r); err != nil || buf.Len() != 0 {
+			t.Errorf("printRunResult(%v) = %v, %v, want nothing", f, buf.String(), err)
+		}
+	}
+}
+
+func Test_runResult_finish(t *testing.T) {
+	r := newRunResult()
+	r.Outcome = outcomeRefreshed
+	r.finish(nil)
+	if r.Outcome != outcomeRefreshed || r.Error != "" {
+		t.Errorf("runResult.finish(nil) = %+v", r)
+	}
+	r.finish(errors.New("something wrong"))
+	if r.Outcome != outcomeFailed || !strings.Contains(r.Error, "something wrong") {
+		t.Errorf("runResult.finish() = %+v", r)
+	}
+}
+
+func Test_parseFormat(t *testing.T) {
+	expiration := time.Now().Add(2*time.Hour + 13*time.Minute + 30*time.Second)
+	r := newRunResult()
+	r.Profile = "sample"
+	r.setParam("duration_seconds", "3600", CliOpt.String())
+	r.Metadata = map[string]string{"issued_at": "2999-11-23T02:15:16Z"}
+	r.Outcome, r.Expiration = outcomeActive, &expiration
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "S01: fields", format: "{{.Profile}} {{.Outcome}}", want: "sample active\n"},
+		{name: "S02: params and metadata", format: "{{.Params.duration_seconds.Value}} ({{.Params.duration_seconds.Source}}) {{.Metadata.issued_at}}\n", want: "3600 (cli option) 2999-11-23T02:15:16Z\n"},
+		{name: "S03: functions", format: "{{.Profile}} {{remaining .Expiration}}", want: "sample 2h13m\n"},
+		{name: "S04: missing key", format: "[{{.Metadata.role_arn}}]", want: "[]\n"},
+		{name: "F01: invalid template", format: "{{.Profile", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, r); err != nil {
+				t.Fatalf("template.Execute() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("template.Execute() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	if got := templateFuncs["remaining"].(func(*time.Time) string)(&expired); got != "EXPIRED" {
+		t.Errorf("remaining() = %v, want EXPIRED", got)
+	}
+}
+
+func Test_runResult_loadMetadata(t *testing.T) {
+	r := newRunResult()
+	r.setParam("output_credentials_file", "testdata/readMetadata_credentials", AwsmfaBuildIn.String())
+	r.setParam("output_profile", "with-metadata", AwsmfaBuildIn.String())
+	r.loadMetadata()
+	if r.Metadata["mode"] != "assume-role" || r.Metadata["source_profile"] != "sample-before-mfa" || len(r.Metadata) != 2 {
+		t.Errorf("runResult.loadMetadata() = %v", r.Metadata)
+	}
+
+	r = newRunResult()
+	r.setParam("output_credentials_file", "testdata/readMetadata_credentials", AwsmfaBuildIn.String())
+	r.setParam("output_profile", "without-metadata", AwsmfaBuildIn.String())
+	r.loadMetadata()
+	if r.Metadata != nil {
+		t.Errorf("runResult.loadMetadata() = %v, want nil", r.Metadata)
+	}
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/ini.v1"
+)
+
+// The explainXxx functions evaluate every layer of the priority chain of a request param in order.
+// The corresponding setXxx selector resolves the value from them, so each priority chain is defined only here.
+
+// paramLayer is a value which a layer of a priority chain holds.
+type paramLayer struct {
+	source string
+	value  string
+	note   string
+	set    bool // The layer holds a value.
+	valid  bool // The value is accepted by the selector.
+	fatal  bool // An invalid value makes the selector fail instead of being ignored.
+}
+
+// paramExplanation is the result of evaluating all layers of a param.
+type paramExplanation struct {
+	name   string
+	layers []paramLayer
+}
+
+// winner returns the index of the layer which the selector uses. It returns -1 if no layer is used.
+func (e paramExplanation) winner() int {
+	for i, l := range e.layers {
+		if l.set && (l.valid || l.fatal) {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve returns the value and the source of the layer which the selector uses.
+// It fails if no layer holds a value, or the used layer holds an invalid value.
+func (e paramExplanation) resolve() (value string, source string, err error) {
+	i := e.winner()
+	if i < 0 {
+		return "", "", fmt.Errorf("no %v specified", e.name)
+	}
+	if l := e.layers[i]; !l.valid {
+		return "", "", fmt.Errorf("invalid %v in %v: %v", e.name, l.source, l.value)
+	}
+	return e.layers[i].value, e.layers[i].source, nil
+}
+
+// value returns the value which the selector uses.
+func (e paramExplanation) value() string {
+	if i := e.winner(); i >= 0 && e.layers[i].valid {
+		return e.layers[i].value
+	}
+	return ""
+}
+
+// result describes why the layer is used or not.
+func (e paramExplanation) result(i int) string {
+	w := e.winner()
+	l := e.layers[i]
+	switch {
+	case i == w && !l.valid:
+		return "ERROR: invalid value"
+	case i == w:
+		return "used"
+	case !l.set:
+		return "not set"
+	case !l.valid:
+		return "ignored: invalid value"
+	case w >= 0:
+		return fmt.Sprintf("overridden by %v", e.layers[w].source)
+	}
+	return "-"
+}
+
+// explainParams evaluates every layer of all request params.
+func explainParams(cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) []paramExplanation {
+	profile := explainProfile(cliProfile, defaultProfile, awsmfaCfg, projectCfg)
+	p := profile.value()
+	beforeMFAProfile := explainBeforeMFAProfile(p, cred, cfg, awsmfaCfg)
+	b := beforeMFAProfile.value()
+
+	mode := explainMode(cliMode, defaultMode, b, cred, cfg, awsmfaCfg, projectCfg)
+	defaultDurationSeconds := defaultDurationSecondsGetSessionToken
+	if mode.value() == "assume-role" {
+		defaultDurationSeconds = defaultDurationSecondsAssumeRole
+	}
+
+	return []paramExplanation{
+		profile,
+		beforeMFAProfile,
+		mode,
+		explainDurationSeconds(cliDurationSeconds, defaultDurationSeconds, b, cred, cfg, awsmfaCfg),
+		explainMFASerial(cliMfaSerial, b, cred, cfg, awsmfaCfg),
+		explainRoleArn(cliRoleArn, b, cred, cfg, projectCfg),
+		explainRoleSessionName(cliRoleSessionName, defaultRoleSessionName, b, cred, cfg, awsmfaCfg),
+		explainEndpointRegion(cliEndpointRegion, defaultEndpointRegion, p, cred, cfg, awsmfaCfg, projectCfg),
+		explainOutputProfile(cliOutputProfile, p, b, cred, cfg),
+		explainOutputCredentialsFile(cliOutputCredentialsFile, credentialsFilePath, b, cred, cfg),
+		explainSaveMetadata(cliSaveMetadata, false, awsmfaCfg),
+		explainRefreshThreshold(cliMinRemaining, 0, b, cred, cfg, awsmfaCfg),
+	}
+}
+
+// printExplanations prints all layers of each param as a table.
+func printExplanations(explanations []paramExplanation) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Parameter", "Layer", "Value", "Result"})
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetRowLine(true)
+	for _, e := range explanations {
+		for i, l := range e.layers {
+			v := l.value
+			if !l.set {
+				v = "-"
+			} else if l.note != "" {
+				v = fmt.Sprintf("%v (%v)", l.value, l.note)
+			}
+			table.Append([]string{e.name, l.source, v, e.result(i)})
+		}
+	}
+	table.Render()
+}
+
+// explainMode evaluates every layer of setMode.
+func explainMode(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "mode"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: validateMode(cliOpt) == nil, fatal: true})
+	e.layers = append(e.layers, fatalLayer(awsmfaEnvLayer("AWSMFA_MODE", EnvAwsmfaMode, func(v string) bool { return validateMode(v) == nil })))
+
+	hasProject := projectValue(projectCfg, "role_arn") != ""
+	e.layers = append(e.layers, paramLayer{source: ProjectFile.String(), value: "assume-role", note: "role_arn is set", set: hasProject, valid: true})
+	hasCred := cred.Section(profile).HasKey("awsmfa_role_arn")
+	e.layers = append(e.layers, paramLayer{source: SharedCredentials.String(), value: "assume-role", note: "awsmfa_role_arn is set", set: hasCred, valid: true})
+	hasCfg := cfg.Section("profile " + profile).HasKey("awsmfa_role_arn")
+	e.layers = append(e.layers, paramLayer{source: SharedConfig.String(), value: "assume-role", note: "awsmfa_role_arn is set", set: hasCfg, valid: true})
+
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "mode", func(v string) bool { return validateMode(v) == nil }))
+
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: validateMode(defaultValue) == nil, fatal: true})
+
+	return e
+}
+
+// explainProfile evaluates every layer of setProfile.
+func explainProfile(cliOpt string, defaultValue string, awsmfaCfg *ini.File, projectCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "profile"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, envLayer("AWS_PROFILE", EnvAWSProfile))
+	e.layers = append(e.layers, projectLayer(projectCfg, "profile"))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "profile", isNotEmpty))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: true})
+
+	return e
+}
+
+// explainBeforeMFAProfile evaluates every layer of setBeforeMFAProfile.
+func explainBeforeMFAProfile(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "before-mfa profile"}
+
+	credLayer := paramLayer{source: SharedCredentials.String()}
+	for _, sec := range cred.Sections() {
+		if sec.HasKey("awsmfa_destination_profile") && sec.Key("awsmfa_destination_profile").String() == profile {
+			credLayer = paramLayer{source: SharedCredentials.String(), value: sec.Name(), set: true, valid: true}
+			break
+		}
+	}
+	e.layers = append(e.layers, credLayer)
+
+	cfgLayer := paramLayer{source: SharedConfig.String()}
+	for _, sec := range cfg.Sections() {
+		if sec.HasKey("awsmfa_destination_profile") && sec.Key("awsmfa_destination_profile").String() == profile {
+			cfgLayer = paramLayer{source: SharedConfig.String(), value: strings.TrimPrefix(sec.Name(), "profile "), set: true, valid: true}
+			break
+		}
+	}
+	e.layers = append(e.layers, cfgLayer)
+
+	mappingLayer := paramLayer{source: AwsmfaConfig.String(), note: "[profile-mapping]"}
+	templateLayer := paramLayer{source: AwsmfaConfig.String(), note: "destination_profile_template"}
+	if awsmfaCfg != nil {
+		if sec, err := awsmfaCfg.GetSection("profile-mapping"); err == nil {
+			for _, k := range sec.Keys() {
+				if k.String() == profile {
+					mappingLayer.value, mappingLayer.set, mappingLayer.valid = k.Name(), true, true
+					break
+				}
+			}
+		}
+		if v := awsmfaCfg.Section("default-value").Key("destination_profile_template").String(); v != "" {
+			p, ok := matchProfileTemplate(v, profile)
+			templateLayer.value, templateLayer.set, templateLayer.valid = p, true, ok
+			if !ok {
+				templateLayer.value = v
+			}
+		}
+	}
+	e.layers = append(e.layers, mappingLayer, templateLayer)
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: profile + beforeMFASuffix, note: "suffix " + beforeMFASuffix, set: true, valid: true})
+
+	return e
+}
+
+// explainDurationSeconds evaluates every layer of setDurationSeconds.
+func explainDurationSeconds(cliOpt int32, defaultValue int32, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "duration_seconds"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: strconv.Itoa(int(cliOpt)), set: cliOpt != 0, valid: true})
+	e.layers = append(e.layers, fatalLayer(awsmfaEnvLayer("AWSMFA_DURATION_SECONDS", EnvAwsmfaDurationSeconds, isDurationSeconds)))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "duration_seconds", SharedCredentials, isDurationSeconds))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "duration_seconds", SharedConfig, isDurationSeconds))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "duration_seconds", isDurationSeconds))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: strconv.Itoa(int(defaultValue)), set: true, valid: true})
+
+	return e
+}
+
+// explainMFASerial evaluates every layer of setMFASerial.
+func explainMFASerial(cliOpt string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "mfa_serial"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_SERIAL_NUMBER", EnvAwsmfaSerialNumber, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "mfa_serial", SharedCredentials, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "mfa_serial", SharedConfig, isNotEmpty))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "mfa_serial", isNotEmpty))
+
+	return e
+}
+
+// explainRoleArn evaluates every layer of setRoleArn.
+func explainRoleArn(cliOpt string, profile string, cred *ini.File, cfg *ini.File, projectCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "awsmfa_role_arn"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_ROLE_ARN", EnvAwsmfaRoleArn, isNotEmpty))
+	e.layers = append(e.layers, projectLayer(projectCfg, "role_arn"))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "awsmfa_role_arn", SharedCredentials, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "awsmfa_role_arn", SharedConfig, isNotEmpty))
+
+	return e
+}
+
+// explainOutputProfile evaluates every layer of setOutputProfile.
+func explainOutputProfile(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "output profile"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_OUTPUT_PROFILE", EnvAwsmfaOutputProfile, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "awsmfa_output_profile", SharedCredentials, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "awsmfa_output_profile", SharedConfig, isNotEmpty))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: true})
+
+	return e
+}
+
+// explainOutputCredentialsFile evaluates every layer of setOutputCredentialsFile.
+func explainOutputCredentialsFile(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "output credentials file"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_OUTPUT_CREDENTIALS_FILE", EnvAwsmfaOutputCredentialsFile, isNotEmpty))
+	e.layers = append(e.layers, expandedLayer(keyLayer(cred.Section(profile), "awsmfa_output_credentials_file", SharedCredentials, isNotEmpty)))
+	e.layers = append(e.layers, expandedLayer(keyLayer(cfg.Section("profile "+profile), "awsmfa_output_credentials_file", SharedConfig, isNotEmpty)))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: true})
+
+	return e
+}
+
+// explainRoleSessionName evaluates every layer of setRoleSessionName.
+func explainRoleSessionName(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "role_session_name"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_ROLE_SESSION_NAME", EnvAwsmfaRoleSessionName, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "role_session_name", SharedCredentials, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "role_session_name", SharedConfig, isNotEmpty))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "role_session_name", isNotEmpty))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: true})
+
+	return e
+}
+
+// explainSaveMetadata evaluates every layer of setSaveMetadata.
+func explainSaveMetadata(cliOpt bool, defaultValue bool, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "save_metadata"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: strconv.FormatBool(cliOpt), set: cliOpt, valid: true})
+	e.layers = append(e.layers, fatalLayer(awsmfaEnvLayer("AWSMFA_SAVE_METADATA", EnvAwsmfaSaveMetadata, isBool)))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "save_metadata", isBool))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: strconv.FormatBool(defaultValue), set: true, valid: true})
+
+	return e
+}
+
+// explainRefreshThreshold evaluates every layer of setRefreshThreshold.
+func explainRefreshThreshold(cliOpt time.Duration, defaultValue time.Duration, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "refresh_threshold"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt.String(), set: cliOpt != 0, valid: cliOpt >= 0, fatal: true})
+	e.layers = append(e.layers, fatalLayer(awsmfaEnvLayer("AWSMFA_MIN_REMAINING", EnvAwsmfaMinRemaining, isThreshold)))
+	e.layers = append(e.layers, negativeThresholdLayer(keyLayer(cred.Section(profile), "refresh_threshold", SharedCredentials, isThreshold)))
+	e.layers = append(e.layers, negativeThresholdLayer(keyLayer(cfg.Section("profile "+profile), "refresh_threshold", SharedConfig, isThreshold)))
+	e.layers = append(e.layers, negativeThresholdLayer(awsmfaConfigLayer(awsmfaCfg, "refresh_threshold", isThreshold)))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue.String(), set: true, valid: true})
+
+	return e
+}
+
+// explainEndpointRegion evaluates every layer of setEndpointRegion.
+func explainEndpointRegion(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) paramExplanation {
+	e := paramExplanation{name: "endpoint_region"}
+
+	e.layers = append(e.layers, paramLayer{source: CliOpt.String(), value: cliOpt, set: cliOpt != "", valid: true})
+	e.layers = append(e.layers, awsmfaEnvLayer("AWSMFA_ENDPOINT_REGION", EnvAwsmfaEndpointRegion, isNotEmpty))
+	e.layers = append(e.layers, envLayer("AWS_REGION", EnvAWSRegion))
+	e.layers = append(e.layers, envLayer("AWS_DEFAULT_REGION", EnvAWSDefaultRegion))
+	e.layers = append(e.layers, projectLayer(projectCfg, "region"))
+	beforeMFAProfile, _ := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	e.layers = append(e.layers, keyLayer(cred.Section(beforeMFAProfile), "region", SharedCredentialsBeforeMFAProfile, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+beforeMFAProfile), "region", SharedConfigBeforeMFAProfile, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cred.Section(profile), "region", SharedCredentialsAfterMFAProfile, isNotEmpty))
+	e.layers = append(e.layers, keyLayer(cfg.Section("profile "+profile), "region", SharedConfigAfterMFAProfile, isNotEmpty))
+	e.layers = append(e.layers, awsmfaConfigLayer(awsmfaCfg, "region", isNotEmpty))
+	e.layers = append(e.layers, paramLayer{source: AwsmfaBuildIn.String(), value: defaultValue, set: true, valid: true})
+
+	return e
+}
+
+// envLayer returns a layer of an environment variable.
+func envLayer(name string, source paramSource) paramLayer {
+	env, exists := os.LookupEnv(name)
+	return paramLayer{source: source.String(), value: env, set: exists, valid: true}
+}
+
+// awsmfaEnvLayer returns a layer of an environment variable of awsmfa. An empty variable is regarded as not set.
+func awsmfaEnvLayer(name string, source paramSource, valid func(string) bool) paramLayer {
+	env := os.Getenv(name)
+	return paramLayer{source: source.String(), value: env, set: env != "", valid: valid(env)}
+}
+
+// fatalLayer makes an invalid value of the layer fail the selector instead of being ignored.
+// Environment variables of awsmfa are explicitly set for the run, so a typo in them should not fall through to the next layer silently.
+func fatalLayer(l paramLayer) paramLayer {
+	l.fatal = true
+	return l
+}
+
+// negativeThresholdLayer makes a negative threshold fail the selector instead of being ignored,
+// because falling through to another layer would hide that the file asks to keep expired tokens.
+func negativeThresholdLayer(l paramLayer) paramLayer {
+	if l.set && strings.HasPrefix(strings.TrimSpace(l.value), "-") {
+		l.fatal = true
+	}
+	return l
+}
+
+// keyLayer returns a layer of a key in a section of the shared credentials/config file.
+// An empty key is regarded as not set because ini.Section.Key creates an empty key when it doesn't exist.
+func keyLayer(sec *ini.Section, key string, source paramSource, valid func(string) bool) paramLayer {
+	if !sec.HasKey(key) || sec.Key(key).String() == "" {
+		return paramLayer{source: source.String()}
+	}
+	v := sec.Key(key).String()
+	return paramLayer{source: source.String(), value: v, set: true, valid: valid(v)}
+}
+
+// awsmfaConfigLayer returns a layer of a key in [default-value] of awsmfa's configuration file.
+func awsmfaConfigLayer(awsmfaCfg *ini.File, key string, valid func(string) bool) paramLayer {
+	if awsmfaCfg == nil {
+		return paramLayer{source: AwsmfaConfig.String()}
+	}
+	return keyLayer(awsmfaCfg.Section("default-value"), key, AwsmfaConfig, valid)
+}
+
+// expandedLayer expands environment variables and a leading ~ of a path in the layer.
+func expandedLayer(l paramLayer) paramLayer {
+	if l.set {
+		l.value = expandPath(l.value)
+	}
+	return l
+}
+
+// projectLayer returns a layer of a key in the project file.
+func projectLayer(projectCfg *ini.File, key string) paramLayer {
+	if projectCfg == nil {
+		return paramLayer{source: ProjectFile.String()}
+	}
+	return keyLayer(projectCfg.Section(""), key, ProjectFile, isNotEmpty)
+}
+
+// isDurationSeconds reports whether the value is integer seconds or a duration in the same way as setDurationSeconds parses it.
+func isDurationSeconds(v string) bool {
+	_, err := parseDurationSeconds(v)
+	return err == nil
+}
+
+// isBool reports whether the value is a boolean in the same way as setSaveMetadata parses it.
+func isBool(v string) bool {
+	_, err := strconv.ParseBool(v)
+	return err == nil
+}
+
+// isThreshold reports whether the value is a duration or integer seconds in the same way as setRefreshThreshold parses it.
+func isThreshold(v string) bool {
+	_, err := parseThreshold(v)
+	return err == nil
+}
+
+// isNotEmpty reports whether the value is not empty.
+func isNotEmpty(v string) bool {
+	return v != ""
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+// loadExplainerTestData loads the test data of the setXxx selectors.
+func loadExplainerTestData(t *testing.T, prefix string, awsmfaCfgSuffix string) (cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) {
+	cred, err := ini.Load("testdata/" + prefix + "_credentials")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	cfg, err = ini.Load("testdata/" + prefix + "_config")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	awsmfaCfg, _ = ini.Load("testdata/" + prefix + "_awsmfaConfiguration_" + awsmfaCfgSuffix)
+	return cred, cfg, awsmfaCfg
+}
+
+// profilesOf returns all profile names in the shared credentials file.
+func profilesOf(cred *ini.File) []string {
+	profiles := []string{}
+	for _, name := range cred.SectionStrings() {
+		if name != ini.DefaultSection {
+			profiles = append(profiles, name)
+		}
+	}
+	return profiles
+}
+
+// winnerOf returns the value and the source of the used layer.
+func winnerOf(e paramExplanation) (value string, source string) {
+	w := e.winner()
+	if w < 0 || !e.layers[w].valid {
+		return "ERROR", "ERROR"
+	}
+	return e.layers[w].value, e.layers[w].source
+}
+
+// Test_explainXxx checks that each explainXxx function picks the same layer as corresponding setXxx selector.
+func Test_explainXxx(t *testing.T) {
+	tests := []struct {
+		name string
+		envs map[string]string
+	}{
+		{name: "S01: no env", envs: map[string]string{}},
+		{name: "S02: awsmfa envs", envs: map[string]string{"AWSMFA_MODE": "assume-role", "AWSMFA_DURATION_SECONDS": "1000", "AWSMFA_SERIAL_NUMBER": "env", "AWSMFA_ROLE_ARN": "env", "AWSMFA_ROLE_SESSION_NAME": "env", "AWSMFA_ENDPOINT_REGION": "env", "AWSMFA_OUTPUT_PROFILE": "env", "AWSMFA_OUTPUT_CREDENTIALS_FILE": "env"}},
+		{name: "S03: invalid awsmfa envs", envs: map[string]string{"AWSMFA_MODE": "wrong-mode💀", "AWSMFA_DURATION_SECONDS": "one hour💀", "AWSMFA_SAVE_METADATA": "yes💀", "AWSMFA_MIN_REMAINING": "soon💀"}},
+		{name: "S04: aws envs", envs: map[string]string{"AWS_REGION": "env", "AWS_DEFAULT_REGION": ""}},
+		{name: "S05: duration env", envs: map[string]string{"AWSMFA_DURATION_SECONDS": "1h30m"}},
+		{name: "S06: save metadata and min remaining envs", envs: map[string]string{"AWSMFA_SAVE_METADATA": "true", "AWSMFA_MIN_REMAINING": "15m"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envs {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			assertExplainersMatchSelectors(t)
+		})
+	}
+}
+
+func assertExplainersMatchSelectors(t *testing.T) {
+	projectCfg, err := ini.Load("testdata/project_awsmfa")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	for _, projectCfg := range []*ini.File{nil, projectCfg} {
+		assertExplainersMatchSelectorsWithProject(t, projectCfg)
+	}
+}
+
+func assertExplainersMatchSelectorsWithProject(t *testing.T, projectCfg *ini.File) {
+	for _, awsmfaCfgSuffix := range []string{"has", "nil", "missing"} {
+		for _, cliOpt := range []string{"", "get-session-token", "wrong-mode💀"} {
+			cred, cfg, awsmfaCfg := loadExplainerTestData(t, "setMode", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainMode(cliOpt, "get-session-token", p, cred, cfg, awsmfaCfg, projectCfg))
+				wantValue, wantSource, _ := setMode(cliOpt, "get-session-token", p, cred, cfg, awsmfaCfg, projectCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainMode(%v, %v, %v) = %v, %v, want %v, %v", cliOpt, p, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+		}
+
+		for _, existsEnv := range []bool{true, false} {
+			func() {
+				defer os.Unsetenv("AWS_PROFILE")
+				if existsEnv {
+					os.Setenv("AWS_PROFILE", "env")
+				}
+				awsmfaCfg, _ := ini.Load("testdata/setProfile_awsmfaConfiguration_" + awsmfaCfgSuffix)
+				gotValue, gotSource := winnerOf(explainProfile("", "default", awsmfaCfg, projectCfg))
+				wantValue, wantSource := setProfile("", "default", awsmfaCfg, projectCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainProfile(%v, %v) = %v, %v, want %v, %v", existsEnv, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+				}
+			}()
+		}
+
+		for _, cliOpt := range []bool{true, false} {
+			awsmfaCfg, _ := ini.Load("testdata/setSaveMetadata_awsmfaConfiguration_" + awsmfaCfgSuffix)
+			gotValue, gotSource := winnerOf(explainSaveMetadata(cliOpt, false, awsmfaCfg))
+			want, wantSource, err := setSaveMetadata(cliOpt, false, awsmfaCfg)
+			wantValue := strconv.FormatBool(want)
+			if err != nil {
+				wantValue = "ERROR"
+			}
+			if gotValue != wantValue || gotSource != wantSource {
+				t.Errorf("explainSaveMetadata(%v, %v) = %v, %v, want %v, %v", cliOpt, awsmfaCfgSuffix, gotValue, gotSource, want, wantSource)
+			}
+		}
+
+		for _, p := range []string{"cred-destination", "config-destination", "mapping-destination", "template-mfa", "unmatched"} {
+			cred, cfg, awsmfaCfg := loadExplainerTestData(t, "setBeforeMFAProfile", awsmfaCfgSuffix)
+			gotValue, gotSource := winnerOf(explainBeforeMFAProfile(p, cred, cfg, awsmfaCfg))
+			wantValue, wantSource := setBeforeMFAProfile(p, cred, cfg, awsmfaCfg)
+			if gotValue != wantValue || gotSource != wantSource {
+				t.Errorf("explainBeforeMFAProfile(%v, %v) = %v, %v, want %v, %v", p, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+			}
+		}
+
+		for _, cliOpt := range []int32{0, 40000} {
+			cred, cfg, awsmfaCfg := loadExplainerTestData(t, "setDurationSeconds", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainDurationSeconds(cliOpt, 5000, p, cred, cfg, awsmfaCfg))
+				got, _ := parseDurationSeconds(gotValue)
+				want, wantSource, _ := setDurationSeconds(cliOpt, 5000, p, cred, cfg, awsmfaCfg)
+				if got != want || gotSource != wantSource {
+					t.Errorf("explainDurationSeconds(%v, %v, %v) = %v, %v, want %v, %v", cliOpt, p, awsmfaCfgSuffix, gotValue, gotSource, want, wantSource)
+				}
+			}
+		}
+
+		for _, cliOpt := range []string{"", "cli"} {
+			cred, cfg, awsmfaCfg := loadExplainerTestData(t, "setMFASerial", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainMFASerial(cliOpt, p, cred, cfg, awsmfaCfg))
+				wantValue, wantSource, _ := setMFASerial(cliOpt, "unspecified", p, cred, cfg, awsmfaCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainMFASerial(%v, %v, %v) = %v, %v, want %v, %v", cliOpt, p, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+
+			cred, cfg, _ = loadExplainerTestData(t, "setRoleArn", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainRoleArn(cliOpt, p, cred, cfg, projectCfg))
+				wantValue, wantSource, _ := setRoleArn(cliOpt, p, cred, cfg, projectCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainRoleArn(%v, %v) = %v, %v, want %v, %v", cliOpt, p, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+
+			cred, cfg, awsmfaCfg = loadExplainerTestData(t, "setRoleSessionName", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainRoleSessionName(cliOpt, "default", p, cred, cfg, awsmfaCfg))
+				wantValue, wantSource := setRoleSessionName(cliOpt, "default", p, cred, cfg, awsmfaCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainRoleSessionName(%v, %v, %v) = %v, %v, want %v, %v", cliOpt, p, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+
+			cred, cfg, _ = loadExplainerTestData(t, "setOutput", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainOutputProfile(cliOpt, "default", p, cred, cfg))
+				wantValue, wantSource := setOutputProfile(cliOpt, "default", p, cred, cfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainOutputProfile(%v, %v) = %v, %v, want %v, %v", cliOpt, p, gotValue, gotSource, wantValue, wantSource)
+				}
+				gotValue, gotSource = winnerOf(explainOutputCredentialsFile(cliOpt, "default", p, cred, cfg))
+				wantValue, wantSource = setOutputCredentialsFile(cliOpt, "default", p, cred, cfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainOutputCredentialsFile(%v, %v) = %v, %v, want %v, %v", cliOpt, p, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+
+			cred, cfg, awsmfaCfg = loadExplainerTestData(t, "setRefreshThreshold", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				gotValue, gotSource := winnerOf(explainRefreshThreshold(0, 0, p, cred, cfg, awsmfaCfg))
+				got, _ := parseThreshold(gotValue)
+				want, wantSource, _ := setRefreshThreshold(0, 0, p, cred, cfg, awsmfaCfg)
+				if got != want || gotSource != wantSource {
+					t.Errorf("explainRefreshThreshold(%v, %v) = %v, %v, want %v, %v", p, awsmfaCfgSuffix, gotValue, gotSource, want, wantSource)
+				}
+			}
+
+			cred, cfg, awsmfaCfg = loadExplainerTestData(t, "setEndpointRegion", awsmfaCfgSuffix)
+			for _, p := range profilesOf(cred) {
+				p = strings.TrimSuffix(p, beforeMFASuffix)
+				gotValue, gotSource := winnerOf(explainEndpointRegion(cliOpt, "default", p, cred, cfg, awsmfaCfg, projectCfg))
+				wantValue, wantSource := setEndpointRegion(cliOpt, "default", p, cred, cfg, awsmfaCfg, projectCfg)
+				if gotValue != wantValue || gotSource != wantSource {
+					t.Errorf("explainEndpointRegion(%v, %v, %v) = %v, %v, want %v, %v", cliOpt, p, awsmfaCfgSuffix, gotValue, gotSource, wantValue, wantSource)
+				}
+			}
+		}
+	}
+}
+
+func Test_paramExplanation_resolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		layers     []paramLayer
+		wantValue  string
+		wantSource string
+		wantErr    bool
+	}{
+		{name: "S01", layers: []paramLayer{{source: "first"}, {source: "second", value: "invalid", set: true}, {source: "third", value: "used", set: true, valid: true}}, wantValue: "used", wantSource: "third"},
+		{name: "F01: no layer", layers: []paramLayer{{source: "first"}}, wantErr: true},
+		{name: "F02: fatal layer", layers: []paramLayer{{source: "first", value: "wrong", set: true, fatal: true}, {source: "second", value: "ok", set: true, valid: true}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotValue, gotSource, err := paramExplanation{name: "test", layers: tt.layers}.resolve()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("paramExplanation.resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotValue != tt.wantValue || gotSource != tt.wantSource {
+				t.Errorf("paramExplanation.resolve() = %v, %v, want %v, %v", gotValue, gotSource, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_paramExplanation_result(t *testing.T) {
+	e := paramExplanation{name: "test", layers: []paramLayer{
+		{source: "first", set: false},
+		{source: "second", value: "invalid", set: true, valid: false},
+		{source: "third", value: "used", set: true, valid: true},
+		{source: "fourth", value: "overridden", set: true, valid: true},
+	}}
+	want := []string{"not set", "ignored: invalid value", "used", "overridden by third"}
+	for i := range e.layers {
+		if got := e.result(i); got != want[i] {
+			t.Errorf("paramExplanation.result(%v) = %v, want %v", i, got, want[i])
+		}
+	}
+	if got := e.value(); got != "used" {
+		t.Errorf("paramExplanation.value() = %v, want used", got)
+	}
+
+	fatal := paramExplanation{name: "fatal", layers: []paramLayer{{source: "first", value: "wrong", set: true, valid: false, fatal: true}}}
+	if got := fatal.result(0); got != "ERROR: invalid value" {
+		t.Errorf("paramExplanation.result() = %v, want ERROR: invalid value", got)
+	}
+	if got := fatal.value(); got != "" {
+		t.Errorf("paramExplanation.value() = %v, want empty", got)
+	}
+}
@@ -3,115 +3,103 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
 
+// The setXxx selectors resolve a request param from the layers of its priority chain, which the corresponding explainXxx builds.
+// So the value which awsmfa uses and the one which 'awsmfa explain' shows never differ.
+
 // setMode returns action mode to be used.
 // Priority
 // 1. cli option: --mode
-// 2. shared credentials or config file: if given profile has a role_arn param.
-// 3. awsmfa configuration file: [default-value] profile
-// 4. awsmfa build in default value
+// 2. environment variable: AWSMFA_MODE
+// 3. project file: if it has a role_arn param.
+// 4. shared credentials or config file: if given profile has a role_arn param.
+// 5. awsmfa configuration file: [default-value] profile
+// 6. awsmfa build in default value
 // If the mode is not whether 'get-session-token' or 'assume-role', awsmfa returns an error.
-func setMode(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (mode string, source string, err error) {
-	if cliOpt == "get-session-token" || cliOpt == "assume-role" {
-		return cliOpt, CliOpt.String(), nil
-	} else if cliOpt != "" {
-		return "ERROR", "ERROR", fmt.Errorf("invalid action mode: action mode should be \"get-session-token\" or \"assume-role\"")
-	}
-
-	if cred.Section(profile).HasKey("awsmfa_role_arn") {
-		return "assume-role", SharedCredentials.String(), nil
-	}
-
-	if cfg.Section("profile " + profile).HasKey("awsmfa_role_arn") {
-		return "assume-role", SharedConfig.String(), nil
+func setMode(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) (mode string, source string, err error) {
+	v, source, err := explainMode(cliOpt, defaultValue, profile, cred, cfg, awsmfaCfg, projectCfg).resolve()
+	if err != nil {
+		return "ERROR", "ERROR", fmt.Errorf("%w. The action mode should be \"get-session-token\" or \"assume-role\"", err)
 	}
-
-	if awsmfaCfg != nil {
-		if v := awsmfaCfg.Section("default-value").Key("mode").String(); v == "get-session-token" || v == "assume-role" {
-			return v, AwsmfaConfig.String(), nil
-		}
-	}
-
-	if defaultValue == "get-session-token" || defaultValue == "assume-role" {
-		return defaultValue, AwsmfaBuildIn.String(), nil
-	}
-
-	return "ERROR", "ERROR", fmt.Errorf("invalid action mode: action mode should be \"get-session-token\" or \"assume-role\"")
+	return v, source, nil
 }
 
 // setProfile returns a profile to be used.
 // Priority
 // 1. cli option: --profile
 // 2. environment variable: AWS_PROFILE
-// 3. awsmfa configuration file: [default-value] profile
-// 4. awsmfa build in default value
-func setProfile(cliOpt string, defaultValue string, awsmfaCfg *ini.File) (mode string, source string) {
-	if cliOpt != "" {
-		return cliOpt, CliOpt.String()
+// 3. project file: the nearest .awsmfa from the current directory (see loadProjectCfg)
+// 4. awsmfa configuration file: [default-value] profile
+// 5. awsmfa build in default value
+func setProfile(cliOpt string, defaultValue string, awsmfaCfg *ini.File, projectCfg *ini.File) (mode string, source string) {
+	v, source, _ := explainProfile(cliOpt, defaultValue, awsmfaCfg, projectCfg).resolve()
+	return v, source
+}
+
+// setBeforeMFAProfile returns the before-mfa profile whose temporary credentials are saved as the profile.
+// Priority
+// 1. shared credentials file: a profile which has awsmfa_destination_profile = profile
+// 2. shared config file: a profile which has awsmfa_destination_profile = profile
+// 3. awsmfa configuration file: [profile-mapping] before-mfa-profile = profile
+// 4. awsmfa configuration file: [default-value] destination_profile_template, such as {profile}-mfa or mfa-{profile}
+// 5. profile + suffix of before-mfa profile: [default-value] suffix_of_before_mfa_profile
+func setBeforeMFAProfile(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (beforeMFAProfile string, source string) {
+	v, source, _ := explainBeforeMFAProfile(profile, cred, cfg, awsmfaCfg).resolve()
+	return v, source
+}
+
+// matchProfileTemplate returns the part of the profile which {profile} of the template stands for.
+func matchProfileTemplate(template string, profile string) (beforeMFAProfile string, ok bool) {
+	i := strings.Index(template, "{profile}")
+	if i < 0 {
+		return "", false
 	}
-	if env, exists := os.LookupEnv("AWS_PROFILE"); exists == true {
-		return env, EnvAWSProfile.String()
+	prefix, suffix := template[:i], template[i+len("{profile}"):]
+	if len(profile) <= len(prefix)+len(suffix) || !strings.HasPrefix(profile, prefix) || !strings.HasSuffix(profile, suffix) {
+		return "", false
 	}
-	if awsmfaCfg != nil {
-		if v := awsmfaCfg.Section("default-value").Key("profile").String(); v != "" {
-			return v, AwsmfaConfig.String()
-		}
-	}
-	return defaultValue, AwsmfaBuildIn.String()
+	return profile[len(prefix) : len(profile)-len(suffix)], true
 }
 
 // setDurationSeconds returns duration seconds to be used.
+// The value is either integer seconds or a duration such as 12h, 90m and 1h30m.
 // Priority
 // 1. cli option: --duration-seconds
-// 2. shared credentials file: ${HOME}/.aws/credentials (by default)
-// 3. shared config file: ${HOME}/.aws/config (by default)
-// 4. awsmfa configuration file: [default-value] duration_seconds
-// 5. awsmfa build in default value
-func setDurationSeconds(cliOpt int32, defaultValue int32, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (duration int32, source string) {
-	if cliOpt != 0 {
-		return cliOpt, CliOpt.String()
-	}
-	if v, err := cred.Section(profile).Key("duration_seconds").Int(); err == nil {
-		return int32(v), SharedCredentials.String()
+// 2. environment variable: AWSMFA_DURATION_SECONDS (an invalid value is an error)
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa configuration file: [default-value] duration_seconds
+// 6. awsmfa build in default value
+func setDurationSeconds(cliOpt int32, defaultValue int32, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (duration int32, source string, err error) {
+	v, source, err := explainDurationSeconds(cliOpt, defaultValue, profile, cred, cfg, awsmfaCfg).resolve()
+	if err != nil {
+		return 0, "ERROR", err
 	}
-	if v, err := cfg.Section("profile " + profile).Key("duration_seconds").Int(); err == nil {
-		return int32(v), SharedConfig.String()
-	}
-	if awsmfaCfg != nil {
-		if v, err := awsmfaCfg.Section("default-value").Key("duration_seconds").Int(); err == nil {
-			return int32(v), AwsmfaConfig.String()
-		}
-	}
-	return defaultValue, AwsmfaBuildIn.String()
+	d, _ := parseDurationSeconds(v)
+	return d, source, nil
 }
 
 // setMFASerial returns mfa device's serial number to be used.
 // Priority
 // 1. cli option: --serial-number
-// 2. shared credentials file: ${HOME}/.aws/credentials (by default)
-// 3. shared config file: ${HOME}/.aws/config (by default)
-// 4. awsmfa configuration file: [default-value] duration_seconds
+// 2. environment variable: AWSMFA_SERIAL_NUMBER
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa configuration file: [default-value] duration_seconds
 // If any serial number is not specified, setMFASerial returns error.
 func setMFASerial(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (serial string, source string, err error) {
-	if cliOpt != "" {
-		return cliOpt, CliOpt.String(), nil
+	v, source, err := explainMFASerial(cliOpt, profile, cred, cfg, awsmfaCfg).resolve()
+	if err != nil {
+		return "ERROR", "ERROR", err
 	}
-	if v := cred.Section(profile).Key("mfa_serial").String(); v != "" {
-		return v, SharedCredentials.String(), nil
-	}
-	if v := cfg.Section("profile " + profile).Key("mfa_serial").String(); v != "" {
-		return v, SharedConfig.String(), nil
-	}
-	if awsmfaCfg != nil {
-		if v := awsmfaCfg.Section("default-value").Key("mfa_serial").String(); v != "" {
-			return v, AwsmfaConfig.String(), nil
-		}
-	}
-
-	return "ERROR", "ERROR", fmt.Errorf("no mfa_serial specified")
+	return v, source, nil
 }
 
 // setRoleArn returns a role arn related to given profile.
@@ -119,84 +107,176 @@ func setMFASerial(cliOpt string, defaultValue string, profile string, cred *ini.
 // not aws build in parameter 'role_arn'.
 // Priority
 // 1. cli option: --role-arn
-// 2. shared credentials file: ${HOME}/.aws/credentials (by default)
-// 3. shared config file: ${HOME}/.aws/config (by default)
+// 2. environment variable: AWSMFA_ROLE_ARN
+// 3. project file: role_arn
+// 4. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 5. shared config file: ${HOME}/.aws/config (by default)
 // If any arn is not specified, setRoleArn returns error.
-func setRoleArn(cliOpt string, profile string, cred *ini.File, cfg *ini.File) (roleArn string, source string, err error) {
-	if cliOpt != "" {
-		return cliOpt, CliOpt.String(), nil
-	}
-	if v := cred.Section(profile).Key("awsmfa_role_arn").String(); v != "" {
-		return v, SharedCredentials.String(), nil
-	}
-	if v := cfg.Section("profile " + profile).Key("awsmfa_role_arn").String(); v != "" {
-		return v, SharedConfig.String(), nil
+func setRoleArn(cliOpt string, profile string, cred *ini.File, cfg *ini.File, projectCfg *ini.File) (roleArn string, source string, err error) {
+	v, source, err := explainRoleArn(cliOpt, profile, cred, cfg, projectCfg).resolve()
+	if err != nil {
+		return "ERROR", "ERROR", err
 	}
-
-	return "ERROR", "ERROR", fmt.Errorf("no awsmfa_role_arn specified")
+	return v, source, nil
 }
 
 // setRoleSessionName returns a role session name to be used.
 // Priority
 // 1. cli option: --role-session-name
-// 2. shared credentials file: ${HOME}/.aws/credentials (by default)
-// 3. shared config file: ${HOME}/.aws/config (by default)
-// 4. awsmfa configuration file: [default-value] role_session_name
-// 5. awsmfa build in default value
+// 2. environment variable: AWSMFA_ROLE_SESSION_NAME
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa configuration file: [default-value] role_session_name
+// 6. awsmfa build in default value
 func setRoleSessionName(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (roleSessionName string, source string) {
-	if cliOpt != "" {
-		return cliOpt, CliOpt.String()
-	}
-	if v := cred.Section(profile).Key("role_session_name").String(); v != "" {
-		return v, SharedCredentials.String()
-	}
-	if v := cfg.Section("profile " + profile).Key("role_session_name").String(); v != "" {
-		return v, SharedConfig.String()
-	}
-	if awsmfaCfg != nil {
-		if v := awsmfaCfg.Section("default-value").Key("role_session_name").String(); v != "" {
-			return v, AwsmfaConfig.String()
-		}
-	}
-	return defaultValue, AwsmfaBuildIn.String()
+	v, source, _ := explainRoleSessionName(cliOpt, defaultValue, profile, cred, cfg, awsmfaCfg).resolve()
+	return v, source
 }
 
 // setEndpointRegion returns mfa device's serial number to be used.
 // Priority
 // 1. cli option: --endpoint-region
-// 2. environment variable: AWS_REGION
-// 3. environment variable: AWS_DEFAULT_REGION
-// 4. profile-before-mfa in shared credentials file: ${HOME}/.aws/credentials (by default)
-// 5. profile-before-mfa in shared config file: ${HOME}/.aws/config (by default)
-// 6. profile in shared credentials file: ${HOME}/.aws/credentials (by default)
-// 7. profile in shared config file: ${HOME}/.aws/config (by default)
-// 6. awsmfa configuration file: [default-value] duration_seconds (Need to overwrite build in default value in advance)
-// 7. awsmfa build in default value
-func setEndpointRegion(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (endpointRegion string, source string) {
-	if cliOpt != "" {
-		return cliOpt, CliOpt.String()
+// 2. environment variable: AWSMFA_ENDPOINT_REGION
+// 3. environment variable: AWS_REGION
+// 4. environment variable: AWS_DEFAULT_REGION
+// 5. project file: region
+// 6. before-mfa profile (see setBeforeMFAProfile) in shared credentials file: ${HOME}/.aws/credentials (by default)
+// 7. before-mfa profile (see setBeforeMFAProfile) in shared config file: ${HOME}/.aws/config (by default)
+// 8. profile in shared credentials file: ${HOME}/.aws/credentials (by default)
+// 9. profile in shared config file: ${HOME}/.aws/config (by default)
+// 10. awsmfa configuration file: [default-value] duration_seconds (Need to overwrite build in default value in advance)
+// 11. awsmfa build in default value
+func setEndpointRegion(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) (endpointRegion string, source string) {
+	v, source, _ := explainEndpointRegion(cliOpt, defaultValue, profile, cred, cfg, awsmfaCfg, projectCfg).resolve()
+	return v, source
+}
+
+// setOutputProfile returns the profile where temporary credentials are saved.
+// Priority
+// 1. cli option: --output-profile
+// 2. environment variable: AWSMFA_OUTPUT_PROFILE
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa build in default value (the profile)
+func setOutputProfile(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File) (outputProfile string, source string) {
+	v, source, _ := explainOutputProfile(cliOpt, defaultValue, profile, cred, cfg).resolve()
+	return v, source
+}
+
+// setOutputCredentialsFile returns a path of the credentials file where temporary credentials are saved.
+// Priority
+// 1. cli option: --output-credentials-file
+// 2. environment variable: AWSMFA_OUTPUT_CREDENTIALS_FILE
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa build in default value (the shared credentials file)
+// Environment variables and a leading ~ in the files are expanded as [filepath] keys of awsmfa's configuration file.
+func setOutputCredentialsFile(cliOpt string, defaultValue string, profile string, cred *ini.File, cfg *ini.File) (path string, source string) {
+	v, source, _ := explainOutputCredentialsFile(cliOpt, defaultValue, profile, cred, cfg).resolve()
+	return v, source
+}
+
+// setSaveMetadata returns whether session metadata is saved with temporary credentials.
+// Priority
+// 1. cli option: --save-metadata
+// 2. environment variable: AWSMFA_SAVE_METADATA (an invalid value is an error)
+// 3. awsmfa configuration file: [default-value] save_metadata
+// 4. awsmfa build in default value
+func setSaveMetadata(cliOpt bool, defaultValue bool, awsmfaCfg *ini.File) (saveMetadata bool, source string, err error) {
+	v, source, err := explainSaveMetadata(cliOpt, defaultValue, awsmfaCfg).resolve()
+	if err != nil {
+		return false, "ERROR", err
 	}
-	if env, exists := os.LookupEnv("AWS_REGION"); exists == true {
-		return env, EnvAWSRegion.String()
+	b, _ := strconv.ParseBool(v)
+	return b, source, nil
+}
+
+// setRefreshThreshold returns the minimum remaining time of an active token. A token which expires sooner is refreshed.
+// The value in files is either a duration such as 10m or integer seconds.
+// Priority
+// 1. cli option: --min-remaining
+// 2. environment variable: AWSMFA_MIN_REMAINING (an invalid value is an error)
+// 3. shared credentials file: ${HOME}/.aws/credentials (by default)
+// 4. shared config file: ${HOME}/.aws/config (by default)
+// 5. awsmfa configuration file: [default-value] refresh_threshold
+// 6. awsmfa build in default value
+// A negative value in any layer is an error.
+func setRefreshThreshold(cliOpt time.Duration, defaultValue time.Duration, profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File) (threshold time.Duration, source string, err error) {
+	v, source, err := explainRefreshThreshold(cliOpt, defaultValue, profile, cred, cfg, awsmfaCfg).resolve()
+	if err != nil {
+		return 0, "ERROR", err
 	}
-	if env, exists := os.LookupEnv("AWS_DEFAULT_REGION"); exists == true {
-		return env, EnvAWSDefaultRegion.String()
+	d, _ := parseThreshold(v)
+	return d, source, nil
+}
+
+// parseThreshold parses a duration such as 10m or integer seconds.
+// A negative threshold is rejected, because it would regard an expired token as active.
+func parseThreshold(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if sec, serr := strconv.Atoi(v); serr == nil {
+		d, err = time.Duration(sec)*time.Second, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%v is negative", v)
+	}
+	return d, nil
+}
+
+// expandPath expands environment variables and a leading ~ of a path written in files.
+func expandPath(v string) string {
+	v = os.ExpandEnv(v)
+	if v == "~" || strings.HasPrefix(v, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, v[1:])
+		}
 	}
-	if v := cred.Section(profile + beforeMFASuffix).Key("region").String(); v != "" {
-		return v, SharedCredentialsBeforeMFAProfile.String()
+	return v
+}
+
+// setAwsmfaCfgFilePath returns a path of awsmfa's configuration file.
+// Priority
+// 1. environment variable: AWSMFA_CONFIG
+// 2. awsmfa build in default value
+func setAwsmfaCfgFilePath(defaultValue string) (path string, source string) {
+	if env := os.Getenv("AWSMFA_CONFIG"); env != "" {
+		return env, EnvAwsmfaConfig.String()
 	}
-	if v := cfg.Section("profile " + profile + beforeMFASuffix).Key("region").String(); v != "" {
-		return v, SharedConfigBeforeMFAProfile.String()
+	return defaultValue, AwsmfaBuildIn.String()
+}
+
+// setCredentialsFilePath returns a path of the shared credentials file.
+// Priority
+// 1. environment variable: AWS_SHARED_CREDENTIALS_FILE
+// 2. awsmfa configuration file: [filepath] credentials_file_path
+// 3. awsmfa build in default value
+func setCredentialsFilePath(defaultValue string, awsmfaCfg *ini.File) (path string, source string) {
+	if env := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); env != "" {
+		return env, EnvAWSSharedCredentialsFile.String()
 	}
-	if v := cred.Section(profile).Key("region").String(); v != "" {
-		return v, SharedCredentialsAfterMFAProfile.String()
+	if awsmfaCfg != nil {
+		if v := awsmfaCfg.Section("filepath").Key("credentials_file_path").String(); v != "" {
+			return expandPath(v), AwsmfaConfig.String()
+		}
 	}
-	if v := cfg.Section("profile " + profile).Key("region").String(); v != "" {
-		return v, SharedConfigAfterMFAProfile.String()
+	return defaultValue, AwsmfaBuildIn.String()
+}
+
+// setConfigFilePath returns a path of the shared config file.
+// Priority
+// 1. environment variable: AWS_CONFIG_FILE
+// 2. awsmfa configuration file: [filepath] config_file_path
+// 3. awsmfa build in default value
+func setConfigFilePath(defaultValue string, awsmfaCfg *ini.File) (path string, source string) {
+	if env := os.Getenv("AWS_CONFIG_FILE"); env != "" {
+		return env, EnvAWSConfigFile.String()
 	}
 	if awsmfaCfg != nil {
-		if v := awsmfaCfg.Section("default-value").Key("region").String(); v != "" {
-			return v, AwsmfaConfig.String()
+		if v := awsmfaCfg.Section("filepath").Key("config_file_path").String(); v != "" {
+			return expandPath(v), AwsmfaConfig.String()
 		}
 	}
 	return defaultValue, AwsmfaBuildIn.String()
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
@@ -48,7 +50,7 @@ func Test_setMode(t *testing.T) {
 
 			awsmfaCfg, _ := ini.Load(tt.awsmfaCfgFilePath)
 
-			gotMode, gotSource, err := setMode(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg)
+			gotMode, gotSource, err := setMode(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("setMode() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -90,7 +92,7 @@ func Test_setProfile(t *testing.T) {
 			}
 			awsmfaCfg, _ := ini.Load(tt.awsmfaCfgFilePath)
 
-			gotProfile, gotSource := setProfile(tt.args.cliOpt, tt.args.defaultValue, awsmfaCfg)
+			gotProfile, gotSource := setProfile(tt.args.cliOpt, tt.args.defaultValue, awsmfaCfg, nil)
 			if gotProfile != tt.wantProfile {
 				t.Errorf("setProfile() = %v, want %v", gotProfile, tt.wantSource)
 			}
@@ -101,6 +103,74 @@ func Test_setProfile(t *testing.T) {
 	}
 }
 
+func Test_setBeforeMFAProfile(t *testing.T) {
+	tests := []struct {
+		name                 string
+		profile              string
+		awsmfaCfgFilePath    string
+		wantBeforeMFAProfile string
+		wantSource           string
+	}{
+		{name: "S01", profile: "cred-destination", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "cred-source", wantSource: SharedCredentials.String()},
+		{name: "S02", profile: "both-destination", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "both-source", wantSource: SharedCredentials.String()},
+		{name: "S03", profile: "config-destination", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "config-source", wantSource: SharedConfig.String()},
+		{name: "S04", profile: "mapping-destination", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "mapping-source", wantSource: AwsmfaConfig.String()},
+		{name: "S05", profile: "template-mfa", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "template", wantSource: AwsmfaConfig.String()},
+		{name: "S06", profile: "unmatched", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_has", wantBeforeMFAProfile: "unmatched" + beforeMFASuffix, wantSource: AwsmfaBuildIn.String()},
+		{name: "S07", profile: "template-mfa", awsmfaCfgFilePath: "testdata/setBeforeMFAProfile_awsmfaConfiguration_nil", wantBeforeMFAProfile: "template-mfa" + beforeMFASuffix, wantSource: AwsmfaBuildIn.String()},
+		{name: "S08", profile: "template-mfa", awsmfaCfgFilePath: "nil", wantBeforeMFAProfile: "template-mfa" + beforeMFASuffix, wantSource: AwsmfaBuildIn.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/setBeforeMFAProfile_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/setBeforeMFAProfile_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			awsmfaCfg, err := ini.Load(tt.awsmfaCfgFilePath)
+			if err != nil {
+				awsmfaCfg = nil
+			}
+
+			gotBeforeMFAProfile, gotSource := setBeforeMFAProfile(tt.profile, cred, cfg, awsmfaCfg)
+			if gotBeforeMFAProfile != tt.wantBeforeMFAProfile {
+				t.Errorf("setBeforeMFAProfile() = %v, want %v", gotBeforeMFAProfile, tt.wantBeforeMFAProfile)
+			}
+			if gotSource != tt.wantSource {
+				t.Errorf("setBeforeMFAProfile() = %v, want %v", gotSource, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_matchProfileTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		profile  string
+		want     string
+		wantOk   bool
+	}{
+		{name: "S01: suffix", template: "{profile}-mfa", profile: "dev-mfa", want: "dev", wantOk: true},
+		{name: "S02: prefix", template: "mfa-{profile}", profile: "mfa-dev", want: "dev", wantOk: true},
+		{name: "S03: prefix and suffix", template: "mfa-{profile}-session", profile: "mfa-dev-session", want: "dev", wantOk: true},
+		{name: "F01: unmatched", template: "{profile}-mfa", profile: "dev", want: "", wantOk: false},
+		{name: "F02: empty profile", template: "{profile}-mfa", profile: "-mfa", want: "", wantOk: false},
+		{name: "F03: no placeholder", template: "mfa", profile: "mfa", want: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := matchProfileTemplate(tt.template, tt.profile)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("matchProfileTemplate() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
 func Test_setDurationSeconds(t *testing.T) {
 	type args struct {
 		cliOpt       int32
@@ -137,7 +207,7 @@ func Test_setDurationSeconds(t *testing.T) {
 
 			awsmfaCfg, _ := ini.Load(tt.awsmfaCfgFilePath)
 
-			gotDuration, gotSource := setDurationSeconds(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg)
+			gotDuration, gotSource, _ := setDurationSeconds(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg)
 			if gotDuration != tt.wantDuration {
 				t.Errorf("setDurationSeconds() = %v, want %v", gotDuration, tt.wantDuration)
 			}
@@ -230,7 +300,7 @@ func Test_setRoleArn(t *testing.T) {
 				t.Errorf("failed to load test data: %v", tt.cfgFilePath)
 			}
 
-			gotRoleArn, gotSource, err := setRoleArn(tt.args.cliRoleArn, tt.args.profile, cred, cfg)
+			gotRoleArn, gotSource, err := setRoleArn(tt.args.cliRoleArn, tt.args.profile, cred, cfg, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("setRoleArn() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -344,7 +414,7 @@ func Test_setEndpointRegion(t *testing.T) {
 
 			awsmfaCfg, _ := ini.Load(tt.awsmfaCfgFilePath)
 
-			gotEndpointRegion, gotSource := setEndpointRegion(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg)
+			gotEndpointRegion, gotSource := setEndpointRegion(tt.args.cliOpt, tt.args.defaultValue, tt.args.profile, cred, cfg, awsmfaCfg, nil)
 			if gotEndpointRegion != tt.wantEndpointRegion {
 				t.Errorf("setEndpointRegion() gotEndpointRegion = %v, wantEndpointRegion %v", gotEndpointRegion, tt.wantEndpointRegion)
 			}
@@ -354,3 +424,336 @@ func Test_setEndpointRegion(t *testing.T) {
 		})
 	}
 }
+
+func Test_setXxx_awsmfaEnv(t *testing.T) {
+	load := func(prefix string) (*ini.File, *ini.File, *ini.File) {
+		cred, err := ini.Load("testdata/" + prefix + "_credentials")
+		if err != nil {
+			t.Errorf("failed to load test data: %v", err)
+		}
+		cfg, err := ini.Load("testdata/" + prefix + "_config")
+		if err != nil {
+			t.Errorf("failed to load test data: %v", err)
+		}
+		awsmfaCfg, _ := ini.Load("testdata/" + prefix + "_awsmfaConfiguration_has")
+		return cred, cfg, awsmfaCfg
+	}
+
+	tests := []struct {
+		name       string
+		env        string
+		envValue   string
+		cliOpt     string
+		selector   func(cliOpt string) (value string, source string, err error)
+		wantValue  string
+		wantSource string
+		wantErr    bool
+	}{
+		{name: "S01: mode", env: "AWSMFA_MODE", envValue: "get-session-token", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setMode")
+			return setMode(cliOpt, "get-session-token", "credhas-confighas", cred, cfg, awsmfaCfg, nil)
+		}, wantValue: "get-session-token", wantSource: EnvAwsmfaMode.String()},
+		{name: "S02: mode cli option", env: "AWSMFA_MODE", envValue: "get-session-token", cliOpt: "assume-role", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setMode")
+			return setMode(cliOpt, "get-session-token", "credhas-confighas", cred, cfg, awsmfaCfg, nil)
+		}, wantValue: "assume-role", wantSource: CliOpt.String()},
+		{name: "S03: mode empty env", env: "AWSMFA_MODE", envValue: "", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setMode")
+			return setMode(cliOpt, "get-session-token", "credhas-confighas", cred, cfg, awsmfaCfg, nil)
+		}, wantValue: "assume-role", wantSource: SharedCredentials.String()},
+		{name: "F01: mode invalid env", env: "AWSMFA_MODE", envValue: "wrong-mode💀", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setMode")
+			return setMode(cliOpt, "get-session-token", "credhas-confighas", cred, cfg, awsmfaCfg, nil)
+		}, wantValue: "ERROR", wantSource: "ERROR", wantErr: true},
+		{name: "S04: duration seconds", env: "AWSMFA_DURATION_SECONDS", envValue: "1000", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setDurationSeconds")
+			v, s, err := setDurationSeconds(0, 5000, "cred30000-config20000", cred, cfg, awsmfaCfg)
+			return strconv.Itoa(int(v)), s, err
+		}, wantValue: "1000", wantSource: EnvAwsmfaDurationSeconds.String()},
+		{name: "F02: duration seconds invalid env", env: "AWSMFA_DURATION_SECONDS", envValue: "one hour💀", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setDurationSeconds")
+			v, s, err := setDurationSeconds(0, 5000, "cred30000-config20000", cred, cfg, awsmfaCfg)
+			return strconv.Itoa(int(v)), s, err
+		}, wantValue: "0", wantSource: "ERROR", wantErr: true},
+		{name: "F03: save metadata invalid env", env: "AWSMFA_SAVE_METADATA", envValue: "yes💀", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			v, s, err := setSaveMetadata(false, false, nil)
+			return strconv.FormatBool(v), s, err
+		}, wantValue: "false", wantSource: "ERROR", wantErr: true},
+		{name: "F04: min remaining invalid env", env: "AWSMFA_MIN_REMAINING", envValue: "soon💀", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setRefreshThreshold")
+			v, s, err := setRefreshThreshold(0, 0, "credhas-confighas", cred, cfg, awsmfaCfg)
+			return v.String(), s, err
+		}, wantValue: "0s", wantSource: "ERROR", wantErr: true},
+		{name: "F05: min remaining negative env", env: "AWSMFA_MIN_REMAINING", envValue: "-1h", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setRefreshThreshold")
+			v, s, err := setRefreshThreshold(0, 0, "credhas-confighas", cred, cfg, awsmfaCfg)
+			return v.String(), s, err
+		}, wantValue: "0s", wantSource: "ERROR", wantErr: true},
+		{name: "S06: serial number", env: "AWSMFA_SERIAL_NUMBER", envValue: "env-serial", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setMFASerial")
+			return setMFASerial(cliOpt, "unspecified", "credhas-confighas", cred, cfg, awsmfaCfg)
+		}, wantValue: "env-serial", wantSource: EnvAwsmfaSerialNumber.String()},
+		{name: "S07: role arn", env: "AWSMFA_ROLE_ARN", envValue: "env-role-arn", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, _ := load("setRoleArn")
+			return setRoleArn(cliOpt, "credhas-confighas", cred, cfg, nil)
+		}, wantValue: "env-role-arn", wantSource: EnvAwsmfaRoleArn.String()},
+		{name: "S08: role session name", env: "AWSMFA_ROLE_SESSION_NAME", envValue: "env-session-name", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			cred, cfg, awsmfaCfg := load("setRoleSessionName")
+			v, s := setRoleSessionName(cliOpt, "default", "credhas-confighas", cred, cfg, awsmfaCfg)
+			return v, s, nil
+		}, wantValue: "env-session-name", wantSource: EnvAwsmfaRoleSessionName.String()},
+		{name: "S09: endpoint region", env: "AWSMFA_ENDPOINT_REGION", envValue: "env-endpoint-region", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			defer os.Unsetenv("AWS_REGION")
+			os.Setenv("AWS_REGION", "env-region")
+			cred, cfg, awsmfaCfg := load("setEndpointRegion")
+			v, s := setEndpointRegion(cliOpt, "default", "before-credhas-confighas_after-credhas-confighas", cred, cfg, awsmfaCfg, nil)
+			return v, s, nil
+		}, wantValue: "env-endpoint-region", wantSource: EnvAwsmfaEndpointRegion.String()},
+		{name: "S10: configuration file", env: "AWSMFA_CONFIG", envValue: "env-config", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			v, s := setAwsmfaCfgFilePath("default")
+			return v, s, nil
+		}, wantValue: "env-config", wantSource: EnvAwsmfaConfig.String()},
+		{name: "S11: configuration file empty env", env: "AWSMFA_CONFIG", envValue: "", cliOpt: "", selector: func(cliOpt string) (string, string, error) {
+			v, s := setAwsmfaCfgFilePath("default")
+			return v, s, nil
+		}, wantValue: "default", wantSource: AwsmfaBuildIn.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv(tt.env)
+			os.Setenv(tt.env, tt.envValue)
+
+			gotValue, gotSource, err := tt.selector(tt.cliOpt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("selector error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotValue != tt.wantValue {
+				t.Errorf("selector value = %v, want %v", gotValue, tt.wantValue)
+			}
+			if gotSource != tt.wantSource {
+				t.Errorf("selector source = %v, want %v", gotSource, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_setCredentialsFilePath_setConfigFilePath(t *testing.T) {
+	tests := []struct {
+		name              string
+		envValue          string
+		awsmfaCfgFilePath string
+		wantCredentials   string
+		wantConfig        string
+		wantSource        string
+	}{
+		{name: "S01: env", envValue: "env", awsmfaCfgFilePath: "testdata/initUserDefault_configuration", wantCredentials: "env", wantConfig: "env", wantSource: "env"},
+		{name: "S02: awsmfa configuration file", envValue: "", awsmfaCfgFilePath: "testdata/initUserDefault_configuration", wantCredentials: "testhome/configuration_credentials_file_path", wantConfig: "testhome/configuration_config_file_path", wantSource: AwsmfaConfig.String()},
+		{name: "S03: build in default", envValue: "", awsmfaCfgFilePath: "unspecified", wantCredentials: "default", wantConfig: "default", wantSource: AwsmfaBuildIn.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+			defer os.Unsetenv("AWS_CONFIG_FILE")
+			defer os.Unsetenv("TESTHOME")
+			os.Setenv("AWS_SHARED_CREDENTIALS_FILE", tt.envValue)
+			os.Setenv("AWS_CONFIG_FILE", tt.envValue)
+			os.Setenv("TESTHOME", "testhome")
+			awsmfaCfg, err := ini.Load(tt.awsmfaCfgFilePath)
+			if err != nil {
+				awsmfaCfg = nil
+			}
+
+			gotCredentials, gotCredentialsSource := setCredentialsFilePath("default", awsmfaCfg)
+			wantCredentialsSource := tt.wantSource
+			if tt.wantSource == "env" {
+				wantCredentialsSource = EnvAWSSharedCredentialsFile.String()
+			}
+			if gotCredentials != tt.wantCredentials || gotCredentialsSource != wantCredentialsSource {
+				t.Errorf("setCredentialsFilePath() = %v, %v, want %v, %v", gotCredentials, gotCredentialsSource, tt.wantCredentials, wantCredentialsSource)
+			}
+
+			gotConfig, gotConfigSource := setConfigFilePath("default", awsmfaCfg)
+			wantConfigSource := tt.wantSource
+			if tt.wantSource == "env" {
+				wantConfigSource = EnvAWSConfigFile.String()
+			}
+			if gotConfig != tt.wantConfig || gotConfigSource != wantConfigSource {
+				t.Errorf("setConfigFilePath() = %v, %v, want %v, %v", gotConfig, gotConfigSource, tt.wantConfig, wantConfigSource)
+			}
+		})
+	}
+}
+
+func Test_setOutputProfile_setOutputCredentialsFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		cliOpt     string
+		envValue   string
+		profile    string
+		wantValue  string
+		wantSource string
+	}{
+		{name: "S01", cliOpt: "cli", envValue: "env", profile: "credhas-confighas", wantValue: "cli", wantSource: CliOpt.String()},
+		{name: "S02", cliOpt: "", envValue: "env", profile: "credhas-confighas", wantValue: "env", wantSource: "env"},
+		{name: "S03", cliOpt: "", envValue: "", profile: "credhas-confighas", wantValue: "cred-output", wantSource: SharedCredentials.String()},
+		{name: "S04", cliOpt: "", envValue: "", profile: "crednil-confighas", wantValue: "config-output", wantSource: SharedConfig.String()},
+		{name: "S05", cliOpt: "", envValue: "", profile: "crednil-confignil", wantValue: "default", wantSource: AwsmfaBuildIn.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("AWSMFA_OUTPUT_PROFILE")
+			defer os.Unsetenv("AWSMFA_OUTPUT_CREDENTIALS_FILE")
+			os.Setenv("AWSMFA_OUTPUT_PROFILE", tt.envValue)
+			os.Setenv("AWSMFA_OUTPUT_CREDENTIALS_FILE", tt.envValue)
+			cred, err := ini.Load("testdata/setOutput_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/setOutput_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			wantProfileSource, wantFileSource := tt.wantSource, tt.wantSource
+			if tt.wantSource == "env" {
+				wantProfileSource, wantFileSource = EnvAwsmfaOutputProfile.String(), EnvAwsmfaOutputCredentialsFile.String()
+			}
+
+			gotProfile, gotSource := setOutputProfile(tt.cliOpt, "default", tt.profile, cred, cfg)
+			if gotProfile != tt.wantValue || gotSource != wantProfileSource {
+				t.Errorf("setOutputProfile() = %v, %v, want %v, %v", gotProfile, gotSource, tt.wantValue, wantProfileSource)
+			}
+
+			wantFile := tt.wantValue
+			if tt.wantSource == SharedCredentials.String() || tt.wantSource == SharedConfig.String() {
+				wantFile = tt.wantValue + "-file"
+			}
+			gotFile, gotSource := setOutputCredentialsFile(tt.cliOpt, "default", tt.profile, cred, cfg)
+			if gotFile != wantFile || gotSource != wantFileSource {
+				t.Errorf("setOutputCredentialsFile() = %v, %v, want %v, %v", gotFile, gotSource, wantFile, wantFileSource)
+			}
+		})
+	}
+}
+
+func Test_setOutputCredentialsFile_expandPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		wantValue  string
+		wantSource string
+	}{
+		{name: "S01: env in credentials", profile: "credexpand-confignil", wantValue: "testhome/cred-output-file", wantSource: SharedCredentials.String()},
+		{name: "S02: ~ in config", profile: "crednil-configexpand", wantValue: "userhome/config-output-file", wantSource: SharedConfig.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("TESTHOME")
+			defer os.Setenv("HOME", os.Getenv("HOME"))
+			os.Setenv("TESTHOME", "testhome")
+			os.Setenv("HOME", "userhome")
+			cred, err := ini.Load("testdata/setOutput_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/setOutput_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			got, gotSource := setOutputCredentialsFile("", "default", tt.profile, cred, cfg)
+			if got != tt.wantValue || gotSource != tt.wantSource {
+				t.Errorf("setOutputCredentialsFile() = %v, %v, want %v, %v", got, gotSource, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_setSaveMetadata(t *testing.T) {
+	tests := []struct {
+		name              string
+		cliOpt            bool
+		envValue          string
+		awsmfaCfgFilePath string
+		want              bool
+		wantSource        string
+	}{
+		{name: "S01", cliOpt: true, envValue: "false", awsmfaCfgFilePath: "testdata/setSaveMetadata_awsmfaConfiguration_has", want: true, wantSource: CliOpt.String()},
+		{name: "S02", cliOpt: false, envValue: "false", awsmfaCfgFilePath: "testdata/setSaveMetadata_awsmfaConfiguration_has", want: false, wantSource: EnvAwsmfaSaveMetadata.String()},
+		{name: "S03", cliOpt: false, envValue: "", awsmfaCfgFilePath: "testdata/setSaveMetadata_awsmfaConfiguration_has", want: true, wantSource: AwsmfaConfig.String()},
+		{name: "S04", cliOpt: false, envValue: "", awsmfaCfgFilePath: "testdata/setSaveMetadata_awsmfaConfiguration_nil", want: false, wantSource: AwsmfaBuildIn.String()},
+		{name: "S05", cliOpt: false, envValue: "", awsmfaCfgFilePath: "nil", want: false, wantSource: AwsmfaBuildIn.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("AWSMFA_SAVE_METADATA")
+			os.Setenv("AWSMFA_SAVE_METADATA", tt.envValue)
+			awsmfaCfg, err := ini.Load(tt.awsmfaCfgFilePath)
+			if err != nil {
+				awsmfaCfg = nil
+			}
+
+			got, gotSource, _ := setSaveMetadata(tt.cliOpt, false, awsmfaCfg)
+			if got != tt.want || gotSource != tt.wantSource {
+				t.Errorf("setSaveMetadata() = %v, %v, want %v, %v", got, gotSource, tt.want, tt.wantSource)
+			}
+			gotValue, gotExplainSource := winnerOf(explainSaveMetadata(tt.cliOpt, false, awsmfaCfg))
+			if gotValue != strconv.FormatBool(tt.want) && gotValue != tt.envValue || gotExplainSource != tt.wantSource {
+				t.Errorf("explainSaveMetadata() = %v, %v, want %v, %v", gotValue, gotExplainSource, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_setRefreshThreshold(t *testing.T) {
+	tests := []struct {
+		name              string
+		cliOpt            time.Duration
+		profile           string
+		awsmfaCfgFilePath string
+		want              time.Duration
+		wantSource        string
+		wantErr           bool
+	}{
+		{name: "S01", cliOpt: time.Hour, profile: "credhas-confighas", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: time.Hour, wantSource: CliOpt.String()},
+		{name: "S02", cliOpt: 0, profile: "credhas-confighas", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 30 * time.Minute, wantSource: SharedCredentials.String()},
+		{name: "S03", cliOpt: 0, profile: "credhas-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 10 * time.Minute, wantSource: SharedCredentials.String()},
+		{name: "S04", cliOpt: 0, profile: "crednil-confighas", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 20 * time.Minute, wantSource: SharedConfig.String()},
+		{name: "S05", cliOpt: 0, profile: "crednil-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 5 * time.Minute, wantSource: AwsmfaConfig.String()},
+		{name: "S06", cliOpt: 0, profile: "credinvalid-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 5 * time.Minute, wantSource: AwsmfaConfig.String()},
+		{name: "S07", cliOpt: 0, profile: "crednil-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_nil", want: 0, wantSource: AwsmfaBuildIn.String()},
+		{name: "S08", cliOpt: 0, profile: "crednil-confignil", awsmfaCfgFilePath: "nil", want: 0, wantSource: AwsmfaBuildIn.String()},
+		{name: "F01", cliOpt: -time.Hour, profile: "credhas-confighas", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 0, wantSource: "ERROR", wantErr: true},
+		{name: "F02", cliOpt: 0, profile: "credneg-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 0, wantSource: "ERROR", wantErr: true},
+		{name: "F03", cliOpt: 0, profile: "crednil-configneg", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_has", want: 0, wantSource: "ERROR", wantErr: true},
+		{name: "F04", cliOpt: 0, profile: "crednil-confignil", awsmfaCfgFilePath: "testdata/setRefreshThreshold_awsmfaConfiguration_negative", want: 0, wantSource: "ERROR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/setRefreshThreshold_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/setRefreshThreshold_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			awsmfaCfg, err := ini.Load(tt.awsmfaCfgFilePath)
+			if err != nil {
+				awsmfaCfg = nil
+			}
+
+			got, gotSource, err := setRefreshThreshold(tt.cliOpt, 0, tt.profile, cred, cfg, awsmfaCfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setRefreshThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || gotSource != tt.wantSource {
+				t.Errorf("setRefreshThreshold() = %v, %v, want %v, %v", got, gotSource, tt.want, tt.wantSource)
+			}
+			_, gotExplainSource := winnerOf(explainRefreshThreshold(tt.cliOpt, 0, tt.profile, cred, cfg, awsmfaCfg))
+			if gotExplainSource != tt.wantSource {
+				t.Errorf("explainRefreshThreshold() source = %v, want %v", gotExplainSource, tt.wantSource)
+			}
+		})
+	}
+}
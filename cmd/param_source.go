@@ -17,6 +17,20 @@ const (
 	EnvAWSDefaultRegion
 	EnvAWSRegion
 	EnvAWSProfile
+	EnvAwsmfaMode
+	EnvAwsmfaDurationSeconds
+	EnvAwsmfaSerialNumber
+	EnvAwsmfaRoleArn
+	EnvAwsmfaRoleSessionName
+	EnvAwsmfaEndpointRegion
+	EnvAwsmfaConfig
+	EnvAWSSharedCredentialsFile
+	EnvAWSConfigFile
+	EnvAwsmfaOutputProfile
+	EnvAwsmfaOutputCredentialsFile
+	EnvAwsmfaSaveMetadata
+	EnvAwsmfaMinRemaining
+	ProjectFile
 )
 
 func (s paramSource) String() string {
@@ -45,6 +59,34 @@ func (s paramSource) String() string {
 		return "env AWS_REGION"
 	case EnvAWSProfile:
 		return "env AWS_PROFILE"
+	case EnvAwsmfaMode:
+		return "env AWSMFA_MODE"
+	case EnvAwsmfaDurationSeconds:
+		return "env AWSMFA_DURATION_SECONDS"
+	case EnvAwsmfaSerialNumber:
+		return "env AWSMFA_SERIAL_NUMBER"
+	case EnvAwsmfaRoleArn:
+		return "env AWSMFA_ROLE_ARN"
+	case EnvAwsmfaRoleSessionName:
+		return "env AWSMFA_ROLE_SESSION_NAME"
+	case EnvAwsmfaEndpointRegion:
+		return "env AWSMFA_ENDPOINT_REGION"
+	case EnvAwsmfaConfig:
+		return "env AWSMFA_CONFIG"
+	case EnvAWSSharedCredentialsFile:
+		return "env AWS_SHARED_CREDENTIALS_FILE"
+	case EnvAWSConfigFile:
+		return "env AWS_CONFIG_FILE"
+	case EnvAwsmfaOutputProfile:
+		return "env AWSMFA_OUTPUT_PROFILE"
+	case EnvAwsmfaOutputCredentialsFile:
+		return "env AWSMFA_OUTPUT_CREDENTIALS_FILE"
+	case EnvAwsmfaSaveMetadata:
+		return "env AWSMFA_SAVE_METADATA"
+	case EnvAwsmfaMinRemaining:
+		return "env AWSMFA_MIN_REMAINING"
+	case ProjectFile:
+		return "project file (.awsmfa)"
 	}
 	return "unknown paramSource"
 }
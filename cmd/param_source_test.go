@@ -19,10 +19,24 @@ func Test_paramSource_String(t *testing.T) {
 		{name: "S10", s: EnvAWSDefaultRegion},
 		{name: "S11", s: EnvAWSRegion},
 		{name: "S12", s: EnvAWSProfile},
+		{name: "S13", s: EnvAwsmfaMode},
+		{name: "S14", s: EnvAwsmfaDurationSeconds},
+		{name: "S15", s: EnvAwsmfaSerialNumber},
+		{name: "S16", s: EnvAwsmfaRoleArn},
+		{name: "S17", s: EnvAwsmfaRoleSessionName},
+		{name: "S18", s: EnvAwsmfaEndpointRegion},
+		{name: "S19", s: EnvAwsmfaConfig},
+		{name: "S20", s: EnvAWSSharedCredentialsFile},
+		{name: "S21", s: EnvAWSConfigFile},
+		{name: "S22", s: EnvAwsmfaOutputProfile},
+		{name: "S23", s: EnvAwsmfaOutputCredentialsFile},
+		{name: "S24", s: EnvAwsmfaSaveMetadata},
+		{name: "S25", s: EnvAwsmfaMinRemaining},
+		{name: "S26", s: ProjectFile},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.s.String(); got == "unknown source" {
+			if got := tt.s.String(); got == "unknown paramSource" {
 				t.Errorf("paramSource.String() = %v", got)
 			}
 		})
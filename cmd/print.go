@@ -2,20 +2,52 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 )
 
+// humanOutput is where messages for humans are printed.
+// It is stderr if the result is printed to stdout in a machine readable format, so that stdout stays clean.
+var humanOutput io.Writer = os.Stdout
+
 func printErrorRed(err error) {
 	red := color.New(color.FgRed).FprintfFunc()
 	red(os.Stderr, fmt.Errorf("\x1b[31m[ERROR]: %w\x1b[0m", err).Error())
 }
 
 func printBlue(str string) {
-	color.Blue(str)
+	printColor(color.FgBlue, str)
 }
 
 func printCyan(str string) {
-	color.Cyan(str)
+	printColor(color.FgCyan, str)
+}
+
+func printYellow(str string) {
+	printColor(color.FgYellow, str)
+}
+
+// printColor prints str to humanOutput in the color with a trailing newline, in the same way as color.Blue and so on.
+func printColor(attr color.Attribute, str string) {
+	if !strings.HasSuffix(str, "\n") {
+		str += "\n"
+	}
+	color.New(attr).Fprint(humanOutput, str)
+}
+
+// printDiff prints lines of diffLines. Added lines are green and removed lines are red.
+func printDiff(lines []string) {
+	for _, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "+"):
+			color.Green(l)
+		case strings.HasPrefix(l, "-"):
+			color.Red(l)
+		default:
+			fmt.Println(l)
+		}
+	}
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/ini.v1"
+)
+
+// projectFileName is the name of the project file, which names the profile, the role and the region for a directory tree.
+// The keys are written without a section, such as:
+//
+//	profile  = dev
+//	role_arn = arn:aws:iam::123456789012:role/developer
+//	region   = ap-northeast-1
+const projectFileName = ".awsmfa"
+
+// allowedProjectsFileName is the file in the directory of awsmfa's configuration file which records the project files allowed by 'awsmfa allow'.
+// A project file in a cloned repository can switch the profile and the role, so it is applied only while its content is the same as when it was allowed.
+//
+// [/home/user/src/repo/.awsmfa]
+// sha256 = 0123abcd...
+const allowedProjectsFileName = "allowed-projects"
+
+// errProjectNotAllowed is returned by loadProjectCfg if the project file is new or has been changed since 'awsmfa allow'.
+var errProjectNotAllowed = errors.New("the project file is not allowed")
+
+// findProjectFile walks up from dir and returns the path of the nearest project file.
+// It returns an empty string if there is no project file.
+// A directory named .awsmfa, such as ${HOME}/.awsmfa where awsmfa keeps its state, is not a project file.
+func findProjectFile(dir string) string {
+	for {
+		path := filepath.Join(dir, projectFileName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadProjectCfg loads the nearest project file from the current directory.
+// The project file is for the profile of the project, so it is applied only if its profile is used.
+// It returns nil if the profile is specified by the cli option or AWS_PROFILE, or if the project file doesn't name the profile,
+// because role_arn and region of the project must not be mixed with the keys of another profile.
+// It also returns nil if there is no project file.
+// It returns errProjectNotAllowed if the project file is not allowed (see allowedProjectsFileName).
+func loadProjectCfg(cliProfile string) (projectCfg *ini.File, path string, err error) {
+	if cliProfile != "" || os.Getenv("AWS_PROFILE") != "" {
+		return nil, "", nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", nil
+	}
+	path = findProjectFile(wd)
+	if path == "" {
+		return nil, "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to load project file %v: %w", path, err)
+	}
+	if !isProjectAllowed(allowedProjectsFilePath(), path, data) {
+		return nil, path, fmt.Errorf("%w: %v is new or has been changed. Review it and run 'awsmfa allow' to use it", errProjectNotAllowed, path)
+	}
+	projectCfg, err = ini.Load(data)
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to load project file %v: %w", path, err)
+	}
+	if _, source := setProfile(cliProfile, defaultProfile, nil, projectCfg); source != ProjectFile.String() {
+		return nil, "", nil
+	}
+	return projectCfg, path, nil
+}
+
+// skipProjectNotAllowed prints a warning and returns nil if err is errProjectNotAllowed,
+// so that awsmfa works as if there is no project file. Other errors are returned as they are.
+func skipProjectNotAllowed(err error) error {
+	if errors.Is(err, errProjectNotAllowed) {
+		printYellow(fmt.Sprintf("[Warning] %v", err))
+		return nil
+	}
+	return err
+}
+
+// allowedProjectsFilePath returns the path of the file which records the allowed project files.
+func allowedProjectsFilePath() string {
+	return awsmfaCfgFileDir + "/" + allowedProjectsFileName
+}
+
+// hashProjectFile returns the hash of the content of the project file.
+func hashProjectFile(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// isProjectAllowed reports whether the project file at projectPath is allowed with the content.
+func isProjectAllowed(allowedPath string, projectPath string, data []byte) bool {
+	f, err := ini.LooseLoad(allowedPath)
+	if err != nil {
+		return false
+	}
+	sec, err := f.GetSection(projectPath)
+	if err != nil {
+		return false
+	}
+	return sec.Key("sha256").String() == hashProjectFile(data)
+}
+
+// allowProject records the project file at projectPath with the hash of the content.
+func allowProject(allowedPath string, projectPath string, data []byte) error {
+	f, err := ini.LooseLoad(allowedPath)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", allowedPath, err)
+	}
+	f.Section(projectPath).Key("sha256").SetValue(hashProjectFile(data))
+	return saveIniAtomicWithDir(f, allowedPath, 0600)
+}
+
+// denyProject removes the project file at projectPath from the allowed project files.
+func denyProject(allowedPath string, projectPath string) error {
+	f, err := ini.LooseLoad(allowedPath)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", allowedPath, err)
+	}
+	f.DeleteSection(projectPath)
+	return saveIniAtomicWithDir(f, allowedPath, 0600)
+}
+
+// projectValue returns the value of the key in the project file. It returns an empty string if projectCfg is nil.
+func projectValue(projectCfg *ini.File, key string) string {
+	if projectCfg == nil {
+		return ""
+	}
+	return projectCfg.Section("").Key(key).String()
+}
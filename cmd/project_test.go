@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+func Test_findProjectFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repo", "src", "pkg")
+	if err := os.MkdirAll(filepath.Join(nested, projectFileName), 0700); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	if got := findProjectFile(nested); got != "" {
+		t.Errorf("findProjectFile() = %v, want no project file because .awsmfa is a directory", got)
+	}
+
+	want := filepath.Join(root, "repo", projectFileName)
+	if err := os.WriteFile(want, []byte("profile = dev\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	if got := findProjectFile(nested); got != want {
+		t.Errorf("findProjectFile() = %v, want %v", got, want)
+	}
+}
+
+func Test_selectorsWithProjectFile(t *testing.T) {
+	projectCfg, err := ini.Load("testdata/project_awsmfa")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	cred, err := ini.Load("testData/setEndpointRegion_credentials")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	cfg, err := ini.Load("testData/setEndpointRegion_config")
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+	profile := "before-credhas-confighas_after-credhas-confighas"
+
+	tests := []struct {
+		name       string
+		env        string
+		selector   func() (value string, source string)
+		wantValue  string
+		wantSource string
+	}{
+		{name: "S01: profile", selector: func() (string, string) {
+			return setProfile("", "default", nil, projectCfg)
+		}, wantValue: "project-profile", wantSource: ProjectFile.String()},
+		{name: "S02: profile env", env: "AWS_PROFILE", selector: func() (string, string) {
+			return setProfile("", "default", nil, projectCfg)
+		}, wantValue: "env", wantSource: EnvAWSProfile.String()},
+		{name: "S03: profile cli option", selector: func() (string, string) {
+			return setProfile("cli", "default", nil, projectCfg)
+		}, wantValue: "cli", wantSource: CliOpt.String()},
+		{name: "S04: mode", selector: func() (string, string) {
+			v, s, _ := setMode("", "get-session-token", profile, cred, cfg, nil, projectCfg)
+			return v, s
+		}, wantValue: "assume-role", wantSource: ProjectFile.String()},
+		{name: "S05: role arn", selector: func() (string, string) {
+			v, s, _ := setRoleArn("", profile, cred, cfg, projectCfg)
+			return v, s
+		}, wantValue: "arn:aws:iam::123456789012:role/project-role", wantSource: ProjectFile.String()},
+		{name: "S06: role arn env", env: "AWSMFA_ROLE_ARN", selector: func() (string, string) {
+			v, s, _ := setRoleArn("", profile, cred, cfg, projectCfg)
+			return v, s
+		}, wantValue: "env", wantSource: EnvAwsmfaRoleArn.String()},
+		{name: "S07: endpoint region", selector: func() (string, string) {
+			return setEndpointRegion("", "default", profile, cred, cfg, nil, projectCfg)
+		}, wantValue: "project-region", wantSource: ProjectFile.String()},
+		{name: "S08: endpoint region env", env: "AWS_REGION", selector: func() (string, string) {
+			return setEndpointRegion("", "default", profile, cred, cfg, nil, projectCfg)
+		}, wantValue: "env", wantSource: EnvAWSRegion.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				os.Setenv(tt.env, "env")
+				defer os.Unsetenv(tt.env)
+			}
+			gotValue, gotSource := tt.selector()
+			if gotValue != tt.wantValue || gotSource != tt.wantSource {
+				t.Errorf("selector = %v, %v, want %v, %v", gotValue, gotSource, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func Test_allowProject(t *testing.T) {
+	dir := t.TempDir()
+	allowedPath := filepath.Join(dir, "awsmfa", allowedProjectsFileName)
+	projectPath := filepath.Join(dir, "repo", projectFileName)
+	data := []byte("profile = dev\n")
+
+	if isProjectAllowed(allowedPath, projectPath, data) {
+		t.Errorf("isProjectAllowed() = true before allowProject()")
+	}
+	if err := allowProject(allowedPath, projectPath, data); err != nil {
+		t.Fatalf("allowProject() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		projectPath string
+		data        []byte
+		want        bool
+	}{
+		{name: "S01: allowed", projectPath: projectPath, data: data, want: true},
+		{name: "F01: changed", projectPath: projectPath, data: []byte("profile = prod\n"), want: false},
+		{name: "F02: another path", projectPath: filepath.Join(dir, "fork", projectFileName), data: data, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProjectAllowed(allowedPath, tt.projectPath, tt.data); got != tt.want {
+				t.Errorf("isProjectAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := denyProject(allowedPath, projectPath); err != nil {
+		t.Fatalf("denyProject() error = %v", err)
+	}
+	if isProjectAllowed(allowedPath, projectPath, data) {
+		t.Errorf("isProjectAllowed() = true after denyProject()")
+	}
+}
+
+func Test_loadProjectCfg(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	if err := os.MkdirAll(repo, 0700); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	projectPath := filepath.Join(repo, projectFileName)
+	if err := os.WriteFile(projectPath, []byte("profile = dev\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get the current directory: %v", err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("failed to change the current directory: %v", err)
+	}
+	defer func(d string) { awsmfaCfgFileDir = d }(awsmfaCfgFileDir)
+	awsmfaCfgFileDir = filepath.Join(dir, "awsmfa")
+
+	projectCfg, _, err := loadProjectCfg("")
+	if !errors.Is(err, errProjectNotAllowed) || projectCfg != nil {
+		t.Errorf("loadProjectCfg() = %v, %v, want errProjectNotAllowed before awsmfa allow", projectCfg, err)
+	}
+
+	if err := runAllowCmd(nil, nil); err != nil {
+		t.Fatalf("runAllowCmd() error = %v", err)
+	}
+	projectCfg, _, err = loadProjectCfg("")
+	if err != nil || projectValue(projectCfg, "profile") != "dev" {
+		t.Errorf("loadProjectCfg() = %v, %v, want the project file after awsmfa allow", projectCfg, err)
+	}
+
+	// The project file is ignored if another profile is used.
+	if projectCfg, _, err = loadProjectCfg("prod"); err != nil || projectCfg != nil {
+		t.Errorf("loadProjectCfg() = %v, %v, want no project file with the cli option", projectCfg, err)
+	}
+	os.Setenv("AWS_PROFILE", "prod")
+	projectCfg, _, err = loadProjectCfg("")
+	os.Unsetenv("AWS_PROFILE")
+	if err != nil || projectCfg != nil {
+		t.Errorf("loadProjectCfg() = %v, %v, want no project file with AWS_PROFILE", projectCfg, err)
+	}
+
+	// The project file without the profile is ignored, because its role and region are not for the profile in use.
+	noProfile := []byte("role_arn = arn:aws:iam::123456789012:role/dev\n")
+	if err := os.WriteFile(projectPath, noProfile, 0600); err != nil {
+		t.Fatalf("failed to change test data: %v", err)
+	}
+	if err := runAllowCmd(nil, nil); err != nil {
+		t.Fatalf("runAllowCmd() error = %v", err)
+	}
+	if projectCfg, _, err = loadProjectCfg(""); err != nil || projectCfg != nil {
+		t.Errorf("loadProjectCfg() = %v, %v, want no project file without the profile", projectCfg, err)
+	}
+
+	if err := os.WriteFile(projectPath, []byte("profile = prod\n"), 0600); err != nil {
+		t.Fatalf("failed to change test data: %v", err)
+	}
+	projectCfg, _, err = loadProjectCfg("")
+	if !errors.Is(err, errProjectNotAllowed) || projectCfg != nil {
+		t.Errorf("loadProjectCfg() = %v, %v, want errProjectNotAllowed after the project file is changed", projectCfg, err)
+	}
+}
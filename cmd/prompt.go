@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// prompt subcommand's input value
+var (
+	cliPromptProfile               string
+	cliPromptOutputProfile         string
+	cliPromptOutputCredentialsFile string
+	cliPromptColor                 string
+	cliPromptWarn                  time.Duration
+	cliPromptCritical              time.Duration
+	cliPromptProjectOnly           bool
+	cliPromptFormat                string
+)
+
+// promptColors are styles of the color escape sequences of the prompt segment.
+// bash and zsh wrap the sequences so that the shell doesn't count them as the width of the prompt.
+var promptColors = []string{"none", "ansi", "bash", "zsh", "tmux"}
+
+// promptLevel is the color level of the prompt segment.
+type promptLevel int
+
+const (
+	promptOK promptLevel = iota
+	promptWarn
+	promptCritical
+)
+
+// NewCmdPrompt returns the prompt command.
+func NewCmdPrompt() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Print a compact status of the temporary token for a shell prompt or a status bar",
+		Long: `Print a compact status of the temporary token for a shell prompt or a status bar, such as 'dev 2h13m' or 'prod EXPIRED'.
+
+awsmfa resolves where the temporary credentials are saved in the same way as 'awsmfa wait', and never calls AWS STS,
+so that it is fast enough to run on every refresh of PS1, starship or tmux status line.
+It prints nothing if the profile has no temporary token.`,
+		Example: `  PS1='$(awsmfa prompt --color bash) \w \$ '
+  set -g status-right '#(awsmfa prompt --profile prod --color tmux)'`,
+		Args: cobra.NoArgs,
+		RunE: runPromptCmd,
+	}
+
+	cmd.Flags().StringVarP(&cliPromptProfile, "profile", "p", "", "The profile whose temporary token is shown. The default value is AWS_PROFILE, the profile in the project file (.awsmfa) or 'default'.")
+	cmd.Flags().StringVar(&cliPromptOutputProfile, "output-profile", "", "The profile where temporary credentials are saved. The default value is the profile.")
+	cmd.Flags().StringVar(&cliPromptOutputCredentialsFile, "output-credentials-file", "", "The credentials file where temporary credentials are saved. The default value is the shared credentials file.")
+	cmd.Flags().StringVar(&cliPromptColor, "color", "none", fmt.Sprintf("The style of colors, %v. bash and zsh wrap escape sequences for PS1.", strings.Join(promptColors, ", ")))
+	cmd.Flags().DurationVar(&cliPromptWarn, "warn", 30*time.Minute, "Show the segment in yellow if the token expires within this duration.")
+	cmd.Flags().BoolVar(&cliPromptProjectOnly, "project-only", false, "Print nothing unless an allowed project file (.awsmfa) is found in the current directory or its parents.")
+	cmd.Flags().DurationVar(&cliPromptCritical, "critical", 10*time.Minute, "Show the segment in red if the token expires within this duration. Expired tokens are always red.")
+	cmd.Flags().StringVar(&cliPromptFormat, "format", "", "Print the status by a Go template such as '{{.Profile}} {{remaining .Expiration}}' instead of the segment. See README for the data. Cannot be used with --color.")
+
+	return cmd
+}
+
+func runPromptCmd(cmd *cobra.Command, args []string) error {
+	if err := validatePromptColor(cliPromptColor); err != nil {
+		return err
+	}
+	var tmpl *template.Template
+	if cmd.Flags().Changed("format") {
+		if cmd.Flags().Changed("color") {
+			return fmt.Errorf("--color and --format cannot be used together")
+		}
+		t, err := parseFormat(cliPromptFormat)
+		if err != nil {
+			return err
+		}
+		tmpl = t
+	}
+
+	// A broken or not allowed project file is reported by awsmfa itself, and the prompt just ignores it.
+	projectCfg, _, _ := loadProjectCfg(cliPromptProfile)
+	if projectCfg == nil && cliPromptProjectOnly {
+		return nil
+	}
+	cred, err := ini.LooseLoad(credentialsFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load credentials file: %w", err)
+	}
+	cfg, err := ini.LooseLoad(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+
+	result := resolveOutputTarget(cliPromptProfile, cliPromptOutputProfile, cliPromptOutputCredentialsFile, cred, cfg, awsmfaCfg, projectCfg)
+	outputProfile, outputFile := result.Params["output_profile"].Value, result.Params["output_credentials_file"].Value
+	output := cred
+	if outputFile != credentialsFilePath {
+		if output, err = ini.LooseLoad(outputFile); err != nil {
+			return fmt.Errorf("failed to load output credentials file: %w", err)
+		}
+	}
+
+	due, ok := tokenExpiration(outputProfile, output)
+	if !ok {
+		return nil
+	}
+	if tmpl != nil {
+		result.Outcome, result.Expiration = promptOutcome(*due, time.Now()), due
+		result.loadMetadata()
+		return tmpl.Execute(os.Stdout, result)
+	}
+	fmt.Fprintln(os.Stdout, promptSegment(result.Profile, *due, time.Now(), cliPromptWarn, cliPromptCritical, cliPromptColor))
+	return nil
+}
+
+func validatePromptColor(color string) error {
+	for _, c := range promptColors {
+		if color == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid color style: %v. Please use one of %v", color, promptColors)
+}
+
+// promptOutcome returns the outcome of the status for --format, active or expired.
+func promptOutcome(due time.Time, now time.Time) string {
+	if due.After(now) {
+		return outcomeActive
+	}
+	return outcomeExpired
+}
+
+// promptSegment returns the segment such as 'dev 2h13m' or 'prod EXPIRED' colored by the remaining time.
+func promptSegment(profile string, due time.Time, now time.Time, warn time.Duration, critical time.Duration, color string) string {
+	remaining := due.Sub(now)
+	if remaining <= 0 {
+		return colorPromptSegment(profile+" EXPIRED", promptCritical, color)
+	}
+
+	level := promptOK
+	switch {
+	case remaining <= critical:
+		level = promptCritical
+	case remaining <= warn:
+		level = promptWarn
+	}
+	return colorPromptSegment(profile+" "+humanDuration(remaining), level, color)
+}
+
+// colorPromptSegment wraps the segment by escape sequences of the color style.
+func colorPromptSegment(segment string, level promptLevel, color string) string {
+	ansi := map[promptLevel]string{promptOK: "\x1b[32m", promptWarn: "\x1b[33m", promptCritical: "\x1b[31m"}
+	tmux := map[promptLevel]string{promptOK: "#[fg=green]", promptWarn: "#[fg=yellow]", promptCritical: "#[fg=red]"}
+	const reset = "\x1b[0m"
+
+	switch color {
+	case "ansi":
+		return ansi[level] + segment + reset
+	case "bash":
+		// \[ and \] in the output of a command substitution are not interpreted, so use what readline reads instead.
+		return "\x01" + ansi[level] + "\x02" + segment + "\x01" + reset + "\x02"
+	case "zsh":
+		return "%{" + ansi[level] + "%}" + segment + "%{" + reset + "%}"
+	case "tmux":
+		return tmux[level] + segment + "#[default]"
+	}
+	return segment
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_promptSegment(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		due   time.Time
+		color string
+		want  string
+	}{
+		{name: "S01 active without color", due: now.Add(2*time.Hour + 13*time.Minute + 30*time.Second), color: "none", want: "dev 2h13m"},
+		{name: "S02 expired", due: now.Add(-time.Minute), color: "none", want: "dev EXPIRED"},
+		{name: "S03 ok in ansi", due: now.Add(time.Hour), color: "ansi", want: "\x1b[32mdev 1h0m\x1b[0m"},
+		{name: "S04 warn in bash", due: now.Add(20 * time.Minute), color: "bash", want: "\x01\x1b[33m\x02dev 20m\x01\x1b[0m\x02"},
+		{name: "S05 critical in zsh", due: now.Add(5 * time.Minute), color: "zsh", want: "%{\x1b[31m%}dev 5m%{\x1b[0m%}"},
+		{name: "S06 expired in tmux", due: now, color: "tmux", want: "#[fg=red]dev EXPIRED#[default]"},
+		{name: "S07 far future", due: time.Date(2999, 11, 23, 14, 15, 16, 0, time.UTC), color: "none", want: "dev >365d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptSegment("dev", tt.due, now, 30*time.Minute, 10*time.Minute, tt.color); got != tt.want {
+				t.Errorf("promptSegment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_promptOutcome(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		due  time.Time
+		want string
+	}{
+		{name: "S01: active", due: now.Add(time.Minute), want: outcomeActive},
+		{name: "S02: expired", due: now.Add(-time.Minute), want: outcomeExpired},
+		{name: "S03: expires now", due: now, want: outcomeExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptOutcome(tt.due, now); got != tt.want {
+				t.Errorf("promptOutcome() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validatePromptColor(t *testing.T) {
+	for _, c := range promptColors {
+		if err := validatePromptColor(c); err != nil {
+			t.Errorf("validatePromptColor(%v) error = %v", c, err)
+		}
+	}
+	if err := validatePromptColor("fish💀"); err == nil {
+		t.Errorf("validatePromptColor() error = nil, want error")
+	}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// roleLimitsFileName is the file in the directory of awsmfa's configuration file
+// which remembers what AWS STS told about the max session duration of each role.
+// Only durations confirmed by AssumeRole are recorded: the shortest one rejected and the longest one accepted.
+// The max session duration is between them.
+//
+// [arn:aws:iam::123456789012:role/admin]
+// rejected_duration_seconds = 43200
+// accepted_duration_seconds = 7200
+// checked_at                = 2026-10-16T10:00:00Z
+const roleLimitsFileName = "role-limits"
+
+// roleLimitTTL is how long a record of the role limits file is used.
+// After that, the duration is sent as it is again, so that an extended max session duration is discovered.
+const roleLimitTTL = 7 * 24 * time.Hour
+
+// roleLimit is a record of the role limits file.
+type roleLimit struct {
+	// rejected is the shortest duration rejected by AWS STS. It is 0 if no duration is known to be rejected.
+	rejected int32
+	// accepted is the longest duration accepted by AWS STS.
+	accepted  int32
+	checkedAt time.Time
+}
+
+// minMaxSessionDuration is the lowest max session duration which IAM allows for a role.
+const minMaxSessionDuration int32 = 3600
+
+// NewCmdRoleLimits returns the role-limits command.
+func NewCmdRoleLimits() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "role-limits",
+		Short: "List and clear the max session durations of roles remembered by awsmfa",
+		Long: fmt.Sprintf(`List and clear the max session durations of roles remembered by awsmfa.
+
+When AWS STS rejects the duration of AssumeRole, awsmfa remembers the rejected and the accepted duration of the role in %v,
+and shortens a duration which was rejected before asking your MFA token code. A record is used for 7 days.
+If you have extended the max session duration of a role, clear its record.`, roleLimitsFilePath()),
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the remembered roles",
+		Args:  cobra.NoArgs,
+		RunE:  runRoleLimitsListCmd,
+	}
+	clearCmd := &cobra.Command{
+		Use:   "clear [ROLE_ARN]",
+		Short: "Clear the record of the role, or all records without ROLE_ARN",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runRoleLimitsClearCmd,
+	}
+
+	cmd.AddCommand(listCmd, clearCmd)
+
+	return cmd
+}
+
+func runRoleLimitsListCmd(cmd *cobra.Command, args []string) error {
+	path := roleLimitsFilePath()
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", path, err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Role arn", "Rejected", "Accepted", "Checked at"})
+	now := time.Now()
+	for _, roleArn := range f.SectionStrings() {
+		limit, ok := loadRoleLimit(path, roleArn, now)
+		if !ok {
+			continue
+		}
+		rejected := "-"
+		if limit.rejected > 0 {
+			rejected = fmt.Sprintf("%v sec", limit.rejected)
+		}
+		table.Append([]string{roleArn, rejected, fmt.Sprintf("%v sec", limit.accepted), limit.checkedAt.Local().Format(time.RFC3339)})
+	}
+	table.Render()
+	return nil
+}
+
+func runRoleLimitsClearCmd(cmd *cobra.Command, args []string) error {
+	roleArn := ""
+	if len(args) == 1 {
+		roleArn = args[0]
+	}
+	if err := clearRoleLimit(roleLimitsFilePath(), roleArn); err != nil {
+		return err
+	}
+
+	if roleArn == "" {
+		printCyan(fmt.Sprintf("Successfully cleared all roles in %v\n", roleLimitsFilePath()))
+	} else {
+		printCyan(fmt.Sprintf("Successfully cleared %v in %v\n", roleArn, roleLimitsFilePath()))
+	}
+	return nil
+}
+
+// roleLimitsFilePath returns the path of the file which remembers the max session duration of roles.
+func roleLimitsFilePath() string {
+	return awsmfaCfgFileDir + "/" + roleLimitsFileName
+}
+
+// loadRoleLimit returns the record of the role. A record older than roleLimitTTL is regarded as missing.
+// A record in the old format, which had only max_duration_seconds, is also regarded as missing because it was not confirmed.
+func loadRoleLimit(path string, roleArn string, now time.Time) (limit roleLimit, ok bool) {
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return roleLimit{}, false
+	}
+	sec, err := f.GetSection(roleArn)
+	if err != nil {
+		return roleLimit{}, false
+	}
+	accepted, err := sec.Key("accepted_duration_seconds").Int()
+	if err != nil || accepted <= 0 {
+		return roleLimit{}, false
+	}
+	checkedAt, err := time.Parse(time.RFC3339, sec.Key("checked_at").String())
+	if err != nil || now.Sub(checkedAt) > roleLimitTTL {
+		return roleLimit{}, false
+	}
+	rejected, _ := sec.Key("rejected_duration_seconds").Int()
+	return roleLimit{rejected: int32(rejected), accepted: int32(accepted), checkedAt: checkedAt}, true
+}
+
+// recordRoleLimit merges the durations confirmed by AssumeRole into the record of the role.
+// rejected is the shortest duration rejected in this run, or 0 if none was rejected.
+// Nothing is written if neither this run nor a record tells that the role has a shorter max session duration.
+func recordRoleLimit(path string, roleArn string, rejected int32, accepted int32, now time.Time) error {
+	limit, ok := loadRoleLimit(path, roleArn, now)
+	if !ok && rejected == 0 {
+		return nil
+	}
+	if rejected > 0 && (limit.rejected == 0 || rejected < limit.rejected) {
+		limit.rejected = rejected
+	}
+	if accepted > limit.accepted {
+		limit.accepted = accepted
+	}
+	// The max session duration has been extended if a duration once rejected is accepted now.
+	if limit.rejected <= limit.accepted {
+		limit.rejected = 0
+	}
+
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", path, err)
+	}
+	f.DeleteSection(roleArn)
+	sec := f.Section(roleArn)
+	if limit.rejected > 0 {
+		sec.Key("rejected_duration_seconds").SetValue(fmt.Sprint(limit.rejected))
+	}
+	sec.Key("accepted_duration_seconds").SetValue(fmt.Sprint(limit.accepted))
+	sec.Key("checked_at").SetValue(now.UTC().Format(time.RFC3339))
+	return saveIniAtomicWithDir(f, path, 0600)
+}
+
+// clearRoleLimit removes the record of the role, or all records if roleArn is empty.
+func clearRoleLimit(path string, roleArn string) error {
+	f, err := ini.LooseLoad(path)
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", path, err)
+	}
+	if roleArn == "" {
+		f = ini.Empty()
+	} else {
+		f.DeleteSection(roleArn)
+	}
+	return saveIniAtomicWithDir(f, path, 0600)
+}
+
+// isDurationTooLongError reports whether AWS STS rejected the request because the duration exceeds the max session duration of the role.
+func isDurationTooLongError(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	return ae.ErrorCode() == "ValidationError" && strings.Contains(ae.ErrorMessage(), "MaxSessionDuration")
+}
+
+// shorterDurationSeconds returns the next candidate of the duration which may be accepted by the role.
+// The max session duration of a role is usually set by the hour, so the candidate is the hour below the duration.
+func shorterDurationSeconds(durationSeconds int32) (shorter int32, ok bool) {
+	if durationSeconds <= minMaxSessionDuration {
+		return 0, false
+	}
+	shorter = (durationSeconds - 1) / 3600 * 3600
+	if shorter < minMaxSessionDuration {
+		shorter = minMaxSessionDuration
+	}
+	return shorter, true
+}
+
+// shorterDurationValidator returns a validator of the answer of the duration to retry.
+// The answer should be shorter than the rejected duration and in the range of AssumeRole, or 'n' to abort.
+func shorterDurationValidator(rejected int32) func(string) error {
+	return func(v string) error {
+		if v == "n" {
+			return nil
+		}
+		d, err := parseDurationSeconds(v)
+		if err != nil {
+			return err
+		}
+		if d >= rejected {
+			return fmt.Errorf("the duration should be shorter than %v sec", rejected)
+		}
+		if d < minDurationSeconds {
+			return fmt.Errorf("the duration should be at least %v sec", minDurationSeconds)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+func Test_loadRoleLimit_recordRoleLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "awsmfa", roleLimitsFileName)
+	roleArn := "arn:aws:iam::123456789012:role/admin"
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	if _, ok := loadRoleLimit(path, roleArn, now); ok {
+		t.Errorf("loadRoleLimit() ok = true, want false for missing file")
+	}
+
+	// A run without rejection records nothing.
+	if err := recordRoleLimit(path, roleArn, 0, 43200, now); err != nil {
+		t.Fatalf("recordRoleLimit() error = %v", err)
+	}
+	if _, ok := loadRoleLimit(path, roleArn, now); ok {
+		t.Errorf("loadRoleLimit() ok = true, want false without rejection")
+	}
+
+	tests := []struct {
+		name     string
+		rejected int32
+		accepted int32
+		want     roleLimit
+	}{
+		{name: "S01: rejected and accepted", rejected: 43200, accepted: 3600, want: roleLimit{rejected: 43200, accepted: 3600}},
+		{name: "S02: longer duration accepted", rejected: 0, accepted: 7200, want: roleLimit{rejected: 43200, accepted: 7200}},
+		{name: "S03: shorter duration rejected", rejected: 14400, accepted: 3600, want: roleLimit{rejected: 14400, accepted: 7200}},
+		{name: "S04: rejected duration accepted", rejected: 0, accepted: 14400, want: roleLimit{rejected: 0, accepted: 14400}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := recordRoleLimit(path, roleArn, tt.rejected, tt.accepted, now); err != nil {
+				t.Fatalf("recordRoleLimit() error = %v", err)
+			}
+			got, ok := loadRoleLimit(path, roleArn, now)
+			if !ok || got.rejected != tt.want.rejected || got.accepted != tt.want.accepted || !got.checkedAt.Equal(now) {
+				t.Errorf("loadRoleLimit() = %+v, %v, want %+v, true", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := loadRoleLimit(path, roleArn, now.Add(roleLimitTTL+time.Second)); ok {
+		t.Errorf("loadRoleLimit() ok = true, want false for an expired record")
+	}
+	if _, ok := loadRoleLimit(path, "arn:aws:iam::123456789012:role/unknown", now); ok {
+		t.Errorf("loadRoleLimit() ok = true, want false for unknown role")
+	}
+}
+
+func Test_loadRoleLimit_oldFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), roleLimitsFileName)
+	roleArn := "arn:aws:iam::123456789012:role/admin"
+	if err := os.WriteFile(path, []byte("["+roleArn+"]\nmax_duration_seconds = 3600\n"), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+	if _, ok := loadRoleLimit(path, roleArn, time.Now()); ok {
+		t.Errorf("loadRoleLimit() ok = true, want false for a record which was not confirmed")
+	}
+}
+
+func Test_clearRoleLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), roleLimitsFileName)
+	admin := "arn:aws:iam::123456789012:role/admin"
+	other := "arn:aws:iam::123456789012:role/other"
+	now := time.Now()
+	for _, roleArn := range []string{admin, other} {
+		if err := recordRoleLimit(path, roleArn, 43200, 3600, now); err != nil {
+			t.Fatalf("recordRoleLimit() error = %v", err)
+		}
+	}
+
+	if err := clearRoleLimit(path, admin); err != nil {
+		t.Fatalf("clearRoleLimit() error = %v", err)
+	}
+	if _, ok := loadRoleLimit(path, admin, now); ok {
+		t.Errorf("loadRoleLimit(%v) ok = true after clearRoleLimit()", admin)
+	}
+	if _, ok := loadRoleLimit(path, other, now); !ok {
+		t.Errorf("loadRoleLimit(%v) ok = false, want the record of another role kept", other)
+	}
+
+	if err := clearRoleLimit(path, ""); err != nil {
+		t.Fatalf("clearRoleLimit() error = %v", err)
+	}
+	if _, ok := loadRoleLimit(path, other, now); ok {
+		t.Errorf("loadRoleLimit(%v) ok = true after clearing all records", other)
+	}
+}
+
+func Test_isDurationTooLongError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "S01: max session duration", err: fmt.Errorf("operation error: %w", &smithy.GenericAPIError{Code: "ValidationError", Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role."}), want: true},
+		{name: "S02: another validation error", err: &smithy.GenericAPIError{Code: "ValidationError", Message: "1 validation error detected"}, want: false},
+		{name: "S03: access denied", err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "MultiFactorAuthentication failed"}, want: false},
+		{name: "S04: not api error", err: errors.New("MaxSessionDuration"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDurationTooLongError(tt.err); got != tt.want {
+				t.Errorf("isDurationTooLongError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shorterDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int32
+		want     int32
+		wantOK   bool
+	}{
+		{name: "S01: hours", duration: 43200, want: 39600, wantOK: true},
+		{name: "S02: between hours", duration: 5400, want: 3600, wantOK: true},
+		{name: "S03: lowest max session duration", duration: 3600, want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := shorterDurationSeconds(tt.duration)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("shorterDurationSeconds() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_shorterDurationValidator(t *testing.T) {
+	validate := shorterDurationValidator(7200)
+	for _, v := range []string{"n", "1h", "3600", "15m"} {
+		if err := validate(v); err != nil {
+			t.Errorf("shorterDurationValidator()(%v) error = %v, want nil", v, err)
+		}
+	}
+	for _, v := range []string{"2h", "10m", "one hour"} {
+		if err := validate(v); err == nil {
+			t.Errorf("shorterDurationValidator()(%v) error = nil, want error", v)
+		}
+	}
+}
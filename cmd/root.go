@@ -1,10 +1,14 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
@@ -24,11 +28,21 @@ var (
 	cliEndpointRegion              string
 	cliRoleArn                     string
 	cliRoleSessionName             string
+	cliOutputProfile               string
+	cliOutputCredentialsFile       string
 	cliGenerateCredentialsSkeleton string
 	cliGenerateConfigSkeleton      string
 	cliGenerateConfigurationFile   bool
 	cliForce                       bool
+	cliForceOverwrite              bool
+	cliSaveMetadata                bool
+	cliMinRemaining                time.Duration
+	cliTokenCodeTimeout            time.Duration
+	cliTimeout                     time.Duration
+	cliOutput                      string
+	cliFormat                      string
 	cliSilent                      bool
+	cliDryRun                      bool
 )
 
 // Default values.
@@ -48,20 +62,31 @@ var (
 )
 
 var (
-	awsmfaCfgFileDir  = os.ExpandEnv("$HOME/.awsmfa")
-	awsmfaCfgFileName = "configuration"
-	awsmfaCfgFilePath = awsmfaCfgFileDir + "/" + awsmfaCfgFileName
+	awsmfaCfgFileDir    = os.ExpandEnv("$HOME/.awsmfa")
+	awsmfaCfgFileName   = "configuration"
+	awsmfaCfgFilePath   = awsmfaCfgFileDir + "/" + awsmfaCfgFileName
+	awsmfaCfgFileSource = AwsmfaBuildIn.String()
+)
+
+// Sources of the paths of the shared credentials and config file.
+var (
+	credentialsFilePathSource = AwsmfaBuildIn.String()
+	configFilePathSource      = AwsmfaBuildIn.String()
 )
 
 // Source of request params.
 type source struct {
-	profile         string
-	durationSeconds string
-	mfaSerial       string
-	roleArn         string
-	roleSessionName string
-	endpointRegion  string
-	apiType         string
+	profile          string
+	beforeMFAProfile string
+	durationSeconds  string
+	mfaSerial        string
+	roleArn          string
+	roleSessionName  string
+	endpointRegion   string
+	apiType          string
+	outputProfile    string
+	outputFile       string
+	saveMetadata     string
 }
 
 func initBuildInDefault() {
@@ -75,24 +100,49 @@ func initBuildInDefault() {
 	}
 }
 
+// initAwsmfaCfgFilePath overwrites the path of awsmfa's configuration file by environment variable AWSMFA_CONFIG.
+func initAwsmfaCfgFilePath() {
+	awsmfaCfgFilePath, awsmfaCfgFileSource = setAwsmfaCfgFilePath(awsmfaCfgFilePath)
+	awsmfaCfgFileDir = filepath.Dir(awsmfaCfgFilePath)
+	awsmfaCfgFileName = filepath.Base(awsmfaCfgFilePath)
+}
+
+// initSharedFilePath overwrites the paths of the shared credentials and config file by environment variables
+// AWS_SHARED_CREDENTIALS_FILE and AWS_CONFIG_FILE, in the same way as aws-cli and aws-sdk-go-v2.
+func initSharedFilePath(awsmfaCfgFilePath string) {
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+	credentialsFilePath, credentialsFilePathSource = setCredentialsFilePath(credentialsFilePath, awsmfaCfg)
+	configFilePath, configFilePathSource = setConfigFilePath(configFilePath, awsmfaCfg)
+}
+
 func initUserDefault(awsmfaCfgFilePath string) {
 	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
 	if err != nil {
 		return
 	}
 
-	// Overwrite default values.
-	credentialsFilePath = os.ExpandEnv(awsmfaCfg.Section("filepath").Key("credentials_file_path").String())
-	configFilePath = os.ExpandEnv(awsmfaCfg.Section("filepath").Key("config_file_path").String())
-	beforeMFASuffix = awsmfaCfg.Section("default-value").Key("suffix_of_before_mfa_profile").String()
+	// Overwrite default values only if they are specified.
+	if v := awsmfaCfg.Section("filepath").Key("credentials_file_path").String(); v != "" {
+		credentialsFilePath = expandPath(v)
+	}
+	if v := awsmfaCfg.Section("filepath").Key("config_file_path").String(); v != "" {
+		configFilePath = expandPath(v)
+	}
+	if v := awsmfaCfg.Section("default-value").Key("suffix_of_before_mfa_profile").String(); v != "" {
+		beforeMFASuffix = v
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	cmd := NewCmdRoot()
-	if err := cmd.Execute(); err != nil {
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
 		printErrorRed(err)
+		os.Exit(exitCodeOf(err))
 	}
 }
 
@@ -107,21 +157,24 @@ func NewCmdRoot() *cobra.Command {
 		SilenceErrors: true,
 	}
 
+	initAwsmfaCfgFilePath()
 	cobra.OnInitialize(func() {
 		initBuildInDefault()
 		initUserDefault(awsmfaCfgFilePath)
+		initSharedFilePath(awsmfaCfgFilePath)
 	})
 
 	// Flags
-	cmd.Flags().StringVarP(&cliMode, "mode", "m", "", "The action mode of awsmfa, get-session-token or assume-role. The default value is get-session-token. If you specify the awsmfa_role_arn in shared credentials/config file or --role-arn option, awsmfa automatically turns the mode to assume-role.")
-	cmd.Flags().StringVarP(&cliProfile, "profile", "p", "", "The profile used to get the token. You should set 'xxxx' if you have set 'xxxx-before-mfa' in the shared credentials/config file (.aws/credentials and .aws/config). The default value is 'default'")
-	cmd.Flags().Int32VarP(&cliDurationSeconds, "duration-seconds", "d", 0, "The duration of the temporary security credential. Minimun value: 900 seconds (15 minutes). Max value is different depend on the authentification mode. If you try to get token of same account (with GetSessionToken), Max value is 129600 seconds (36h). In the case of assume role (with AssumeRole), Max value is 43200 seconds (12h). The default value is GetSessionToken=43200 seconds (12h), AssumeRole=3600 seconds (1h).")
-	cmd.Flags().StringVar(&cliMfaSerial, "serial-number", "", "The serial number of the MFA device. The value is either an ARN of a virtual device (arn:aws:iam::123456789012:mfa/user) or the serial number of real device.")
-	cmd.Flags().StringVarP(&cliEndpointRegion, "endpoint-region", "e", "", "The sts endpoint where awsmfa accesses to get a temporary credential. Such as ap-northeast-1, us-east-1.")
-	cmd.Flags().StringVarP(&cliRoleArn, "role-arn", "r", "", "The ARN of the IAM role to assume. If you specify this option, awsmfa automatically turns the mode (--mode, -m) to assume-role.")
-	cmd.Flags().StringVar(&cliRoleSessionName, "role-session-name", "", "The session name which will be logged to the AWS CloudTrail. The default value is awsmfa-session.")
+	addParamFlags(cmd)
 	cmd.Flags().BoolVarP(&cliForce, "force", "f", false, "Force reflesh temporary credentials.")
+	cmd.Flags().DurationVar(&cliMinRemaining, "min-remaining", 0, "Refresh temporary credentials if they expire within this duration, such as 10m or 1h. The default value is 0 (refresh only expired credentials).")
+	cmd.Flags().DurationVar(&cliTimeout, "timeout", time.Minute, "The timeout of each call to AWS, such as resolving credentials and AWS STS API, such as 30s or 1m. 0 means no timeout.")
+	cmd.Flags().DurationVar(&cliTokenCodeTimeout, "token-code-timeout", 3*time.Minute, "How long to wait for the input of the MFA token code, such as 30s or 5m. 0 means no timeout.")
+	cmd.Flags().BoolVar(&cliForceOverwrite, "force-overwrite", false, "Overwrite the profile even if it holds long term credentials which are not managed by awsmfa.")
+	cmd.Flags().StringVarP(&cliOutput, "output", "o", "table", "The format of the result, table, json, yaml or none. With json and yaml, the result is printed to stdout and messages for humans are printed to stderr.")
+	cmd.Flags().StringVar(&cliFormat, "format", "", "Print the result by a Go template such as '{{.Profile}} expires {{.Expiration}}' to stdout. See README for the data.")
 	cmd.Flags().BoolVarP(&cliSilent, "silent", "s", false, "Hide source of request params.")
+	cmd.Flags().BoolVar(&cliDryRun, "dry-run", false, "Show every layer of the priority of request params and exit without calling AWS STS. Same as 'awsmfa explain'.")
 
 	cmd.Flags().StringVar(&cliGenerateCredentialsSkeleton, "generate-credentials-skeleton", "", "Generate skeleton of shared credentials file (by default, ${HOME}/.aws/credentials) for specified action mode, get-session-token or assume-role.")
 	cmd.Flags().StringVar(&cliGenerateConfigSkeleton, "generate-config-skeleton", "", "Generate skeleton of shared config file (by default, ${HOME}/.aws/config). for specified action mode, get-session-token or assume-role.")
@@ -129,11 +182,40 @@ func NewCmdRoot() *cobra.Command {
 
 	// Sub commands
 	cmd.AddCommand(NewCmdCompletion())
+	cmd.AddCommand(NewCmdConfig())
+	cmd.AddCommand(NewCmdInit())
+	cmd.AddCommand(NewCmdDoctor())
+	cmd.AddCommand(NewCmdExplain())
+	cmd.AddCommand(NewCmdWait())
+	cmd.AddCommand(NewCmdPrompt())
+	cmd.AddCommand(NewCmdHook())
+	cmd.AddCommand(NewCmdAllow())
+	cmd.AddCommand(NewCmdDeny())
+	cmd.AddCommand(NewCmdRoleLimits())
 
 	return cmd
 }
 
+// addParamFlags adds cli options of request params.
+func addParamFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&cliMode, "mode", "m", "", "The action mode of awsmfa, get-session-token or assume-role. The default value is get-session-token. If you specify the awsmfa_role_arn in shared credentials/config file or --role-arn option, awsmfa automatically turns the mode to assume-role.")
+	cmd.Flags().StringVarP(&cliProfile, "profile", "p", "", "The profile used to get the token. You should set 'xxxx' if you have set 'xxxx-before-mfa' in the shared credentials/config file (.aws/credentials and .aws/config). The default value is 'default'")
+	cmd.Flags().VarP(durationSecondsValue{&cliDurationSeconds}, "duration-seconds", "d", "The duration of the temporary security credential, in seconds or such as 12h, 90m and 1h30m. Minimun value: 900 seconds (15 minutes). Max value is different depend on the authentification mode. If you try to get token of same account (with GetSessionToken), Max value is 129600 seconds (36h). In the case of assume role (with AssumeRole), Max value is 43200 seconds (12h). The default value is GetSessionToken=43200 seconds (12h), AssumeRole=3600 seconds (1h). The value out of the range of the mode is clamped.")
+	cmd.Flags().Var(untilValue{&cliDurationSeconds}, "until", "The time when the temporary security credential expires, such as 18:30 (the next 18:30) or 2026-10-16T19:00+09:00. The duration seconds are computed from it. Cannot be used with --duration-seconds.")
+	cmd.Flags().StringVar(&cliMfaSerial, "serial-number", "", "The serial number of the MFA device. The value is either an ARN of a virtual device (arn:aws:iam::123456789012:mfa/user) or the serial number of real device.")
+	cmd.Flags().StringVarP(&cliEndpointRegion, "endpoint-region", "e", "", "The sts endpoint where awsmfa accesses to get a temporary credential. Such as ap-northeast-1, us-east-1.")
+	cmd.Flags().StringVarP(&cliRoleArn, "role-arn", "r", "", "The ARN of the IAM role to assume. If you specify this option, awsmfa automatically turns the mode (--mode, -m) to assume-role.")
+	cmd.Flags().StringVar(&cliRoleSessionName, "role-session-name", "", "The session name which will be logged to the AWS CloudTrail. The default value is awsmfa-session.")
+	cmd.Flags().StringVar(&cliOutputProfile, "output-profile", "", "The profile where temporary credentials are saved. The default value is the profile specified by --profile.")
+	cmd.Flags().BoolVar(&cliSaveMetadata, "save-metadata", false, "Save metadata of the session, such as the before-mfa profile, action mode, role arn, MFA device's serial and issued time, with temporary credentials.")
+	cmd.Flags().StringVar(&cliOutputCredentialsFile, "output-credentials-file", "", "The credentials file where temporary credentials are saved, such as a file mounted into containers. The default value is the shared credentials file.")
+}
+
 func runRootCmd(cmd *cobra.Command, args []string) error {
+	if err := checkDurationFlags(cmd); err != nil {
+		return err
+	}
+
 	// If --generate-xxxx-skeleton is specified, show them and terminate.
 	if cliGenerateCredentialsSkeleton != "" {
 		skeleton, err := generateCredentialsSkeleton(cliGenerateCredentialsSkeleton)
@@ -163,14 +245,62 @@ func runRootCmd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if cliDryRun {
+		return runExplainCmd(cmd, args)
+	}
+
+	// Print the result in a machine readable format to stdout, and messages for humans to stderr.
+	// --format renders the result by a Go template instead.
+	if err := validateOutputFormat(cliOutput); err != nil {
+		return err
+	}
+	var tmpl *template.Template
+	if cmd.Flags().Changed("format") {
+		if cmd.Flags().Changed("output") {
+			return fmt.Errorf("--output and --format cannot be used together")
+		}
+		t, err := parseFormat(cliFormat)
+		if err != nil {
+			return err
+		}
+		tmpl = t
+	}
+	if cliOutput != "table" || tmpl != nil {
+		humanOutput = os.Stderr
+	}
+	result := newRunResult()
+	err := refreshCredentials(cmd, result)
+	if err == nil {
+		result.loadMetadata()
+	}
+	result.finish(err)
+	var perr error
+	if tmpl != nil {
+		perr = tmpl.Execute(os.Stdout, result)
+	} else {
+		perr = printRunResult(os.Stdout, cliOutput, result)
+	}
+	if perr != nil {
+		printErrorRed(fmt.Errorf("failed to print the result: %w", perr))
+	}
+	return err
+}
+
+// refreshCredentials refreshes temporary credentials if needed, and records the params and the outcome in result.
+func refreshCredentials(cmd *cobra.Command, result *runResult) error {
+	// Ctrl-C and SIGTERM cancel the run. awsmfa never modifies files after that.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Load credentials, config and awsmfa's configuration files.
 	var source source
 
-	cred, err := ini.Load(credentialsFilePath)
+	// Either file may not exist because the before-mfa profile can be defined only in one of them.
+	cred, err := ini.LooseLoad(credentialsFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load credentials file: %w", err)
 	}
-	cfg, err := ini.Load(configFilePath)
+	cfg, err := ini.LooseLoad(configFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load config file: %w", err)
 	}
@@ -179,41 +309,113 @@ func runRootCmd(cmd *cobra.Command, args []string) error {
 		printBlue(fmt.Sprintf("[Tips] There isn't an awsmfa's configuration file. You can set some default values to place the configuration file at: %v. If you would like to make it by cli, please use 'awsmfa --generate-configuration-file'\n", awsmfaCfgFilePath))
 	}
 
+	projectCfg, projectFile, err := loadProjectCfg(cliProfile)
+	if err := skipProjectNotAllowed(err); err != nil {
+		return err
+	}
+	if projectCfg != nil {
+		printBlue(fmt.Sprintf("[Tips] Using the project file: %v\n", projectFile))
+	}
+
 	// Set target profile.
-	profile, _s := setProfile(cliProfile, defaultProfile, awsmfaCfg)
+	profile, _s := setProfile(cliProfile, defaultProfile, awsmfaCfg, projectCfg)
 	source.profile = _s
+	result.Profile = profile
+	result.setParam("profile", profile, source.profile)
+
+	// Set the before-mfa profile to exec MFA.
+	beforeMFAProfile, _s := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	source.beforeMFAProfile = _s
+	result.setParam("before_mfa_profile", beforeMFAProfile, source.beforeMFAProfile)
 
 	// Check if initial configuration has been completed correctly.
-	if _, err := cred.GetSection(profile + beforeMFASuffix); err != nil {
-		return fmt.Errorf("The profile \"%v%v\" is not set to your credentials file. Please add the profile to %v. You can get template of credentials file by using '--generate-credentials-skeleton get-session-token' or '--generate-credentials-skeleton assume-role'", profile, beforeMFASuffix, credentialsFilePath)
+	// The source credentials may come from long term access keys, credential_process, sso or source_profile,
+	// so awsmfa only checks if aws-sdk-go-v2 has a way to resolve them.
+	if _, err := credentialSourceOf(beforeMFAProfile, cred, cfg); err != nil {
+		return fmt.Errorf("%w. You can get template of credentials/config file by using '--generate-credentials-skeleton get-session-token' or '--generate-config-skeleton get-session-token'", err)
+	}
+
+	// Set the profile and the credentials file where temporary credentials are saved.
+	outputProfile, _s := setOutputProfile(cliOutputProfile, profile, beforeMFAProfile, cred, cfg)
+	source.outputProfile = _s
+	outputFile, _s := setOutputCredentialsFile(cliOutputCredentialsFile, credentialsFilePath, beforeMFAProfile, cred, cfg)
+	source.outputFile = _s
+	saveMetadata, _s, err := setSaveMetadata(cliSaveMetadata, false, awsmfaCfg)
+	source.saveMetadata = _s
+	if err != nil {
+		return err
+	}
+	result.setParam("output_profile", outputProfile, source.outputProfile)
+	result.setParam("output_credentials_file", outputFile, source.outputFile)
+	result.setParam("save_metadata", strconv.FormatBool(saveMetadata), source.saveMetadata)
+	output := cred
+	if outputFile != credentialsFilePath {
+		if output, err = ini.LooseLoad(outputFile); err != nil {
+			return fmt.Errorf("failed to load output credentials file: %w", err)
+		}
 	}
-	if _, err := cfg.GetSection("profile " + profile + beforeMFASuffix); err != nil {
-		return fmt.Errorf("The profile \"%v%v\" is not set to your config file. Please add the profile to %v. You can get template of config file by using '--generate-config-skeleton get-session-token' or '--generate-config-skeleton assume-role'", profile, beforeMFASuffix, configFilePath)
+
+	// Resolve the action mode before judging if reflesh is needed, so that every result has it.
+	// The action mode is forcely turned to "assume-role" if --role-arn is specified or awsmfa_role_arn is specified in your shared credentials/config file.
+	mode, _s, err := setMode(cliMode, defaultMode, beforeMFAProfile, cred, cfg, awsmfaCfg, projectCfg)
+	source.apiType = _s
+	if err != nil {
+		return fmt.Errorf("%w", err)
 	}
+	result.Mode = mode
+	result.setParam("mode", mode, source.apiType)
 
 	// Judge if reflesh is needed.
-	if !cmd.Flags().Lookup("force").Changed {
-		if res, due := hasActiveToken(profile, cred); res == true {
-			printCyan(fmt.Sprintf("Your temporary token is still active. Expired at %v\n", due))
+	// A token which expires within the refresh threshold is refreshed ahead.
+	isForced := cmd.Flags().Lookup("force").Changed
+	minRemaining, _s, err := setRefreshThreshold(cliMinRemaining, 0, beforeMFAProfile, cred, cfg, awsmfaCfg)
+	if err != nil {
+		return err
+	}
+	result.setParam("refresh_threshold", minRemaining.String(), _s)
+	if !isForced {
+		if res, due := hasActiveToken(outputProfile, output, minRemaining); res == true {
+			printCyan(fmt.Sprintf("Your temporary token is still active for %v. Expired at %v\n", humanDuration(time.Until(*due)), due))
+			result.Outcome, result.Expiration = outcomeActive, due
 			return nil
 		}
 	}
 
-	// Execute a handler according to action mode (GetSessionToken or AssumeRole).
-	// The action mode is forcely turned to "assume-role" if --role-arn is specified or awsmfa_role_arn is specified in your shared credentials/config file.
-	mode, _s, err := setMode(cliMode, defaultMode, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
-	source.apiType = _s
+	// Only one process asks the MFA token code per profile.
+	// The others wait for it, and use the temporary credentials it saved.
+	lock, waited, err := acquireProfileLock(ctx, lockFilePath(outputProfile), func(holderPID int) {
+		printBlue(fmt.Sprintf("Waiting for another awsmfa process (pid %v) to refresh the profile %v ...\n", holderPID, outputProfile))
+	})
 	if err != nil {
-		return fmt.Errorf("%w", err)
+		return fmt.Errorf("failed to lock the profile %v: %w", outputProfile, err)
+	}
+	defer lock.release()
+	if waited {
+		if output, err = ini.LooseLoad(outputFile); err != nil {
+			return fmt.Errorf("failed to load output credentials file: %w", err)
+		}
+		if res, due := hasActiveToken(outputProfile, output, minRemaining); res == true {
+			printCyan(fmt.Sprintf("Another awsmfa process has refreshed the temporary token. It is active for %v. Expired at %v\n", humanDuration(time.Until(*due)), due))
+			result.Outcome, result.Expiration = outcomeShared, due
+			return nil
+		}
+	}
+
+	// Protect long term credentials from being overwritten by temporary credentials.
+	if !cliForceOverwrite {
+		if err := checkOverwritable(outputProfile, output); err != nil {
+			return fmt.Errorf("%w. Please specify another profile by --output-profile, or use --force-overwrite if you really want to overwrite it", err)
+		}
 	}
 
+	// Execute a handler according to action mode (GetSessionToken or AssumeRole).
 	switch mode {
 	case "get-session-token":
-		if err := handleGetSessionToken(profile, cred, cfg, awsmfaCfg, &source, cmd.Flags().Lookup("silent").Changed); err != nil {
+		if err := handleGetSessionToken(ctx, profile, beforeMFAProfile, outputProfile, outputFile, saveMetadata, cred, cfg, awsmfaCfg, projectCfg, &source, result, cmd.Flags().Lookup("silent").Changed); err != nil {
 			return fmt.Errorf("failed to get-session-token: %w", err)
 		}
 	case "assume-role":
-		if err := handleAssumeRole(profile, cred, cfg, awsmfaCfg, &source, cmd.Flags().Lookup("silent").Changed); err != nil {
+		if err := handleAssumeRole(ctx, profile, beforeMFAProfile, outputProfile, outputFile, saveMetadata, cred, cfg, awsmfaCfg, projectCfg, &source, result, cmd.Flags().Lookup("silent").Changed); err != nil {
 			return fmt.Errorf("failed to assume-role: %w", err)
 		}
 	default:
@@ -223,36 +425,67 @@ func runRootCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func handleGetSessionToken(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, source *source, isSilent bool) error {
+func handleGetSessionToken(ctx context.Context, profile string, beforeMFAProfile string, outputProfile string, outputFile string, saveMetadata bool, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File, source *source, result *runResult, isSilent bool) error {
 	// Load long term credentials.
 	// To match the priority of credentials and config params (such as access_key) to aws's default order, including environment variables,
 	// awsmfa is sure to reload credentials and config file with aws-sdk-go-v2's build in loading config function before execute GetSessionToken API.
-	c, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithSharedConfigProfile(profile+beforeMFASuffix),
+	loadCtx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	c, err := config.LoadDefaultConfig(loadCtx,
+		config.WithSharedConfigProfile(beforeMFAProfile),
+		config.WithSharedCredentialsFiles([]string{credentialsFilePath}),
+		config.WithSharedConfigFiles([]string{configFilePath}),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to load credentials: %w", err)
 	}
+	// Resolve the source credentials before asking MFA token code, so that a broken credential_process or sso session fails fast.
+	if _, err := c.Credentials.Retrieve(loadCtx); err != nil {
+		return fmt.Errorf("failed to resolve credentials of the profile \"%v\": %w", beforeMFAProfile, err)
+	}
 
 	// Set request params.
-	durationSeconds, _s := setDurationSeconds(cliDurationSeconds, defaultDurationSecondsGetSessionToken, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
+	durationSeconds, _s, err := setDurationSeconds(cliDurationSeconds, defaultDurationSecondsGetSessionToken, beforeMFAProfile, cred, cfg, awsmfaCfg)
 	source.durationSeconds = _s
-	mfaSerial, _s, err := setMFASerial(cliMfaSerial, defaultMFASerial, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
+	if err != nil {
+		return err
+	}
+	if clamped, ok := clampDurationSeconds(durationSeconds, "get-session-token"); !ok {
+		min, max := durationSecondsLimits("get-session-token")
+		printYellow(fmt.Sprintf("[Warning] The duration %v sec is out of the range of GetSessionToken (%v - %v sec). It is clamped to %v sec.", durationSeconds, min, max, clamped))
+		durationSeconds = clamped
+	}
+	mfaSerial, _s, err := setMFASerial(cliMfaSerial, defaultMFASerial, beforeMFAProfile, cred, cfg, awsmfaCfg)
 	source.mfaSerial = _s
 	if err != nil {
 		return fmt.Errorf("The mfa_serial is not specified. You can set it in %v, %v, %v or --serial-number", credentialsFilePath, configFilePath, awsmfaCfgFilePath)
 	}
-	endpointRegion, _s := setEndpointRegion(cliEndpointRegion, defaultEndpointRegion, profile, cred, cfg, awsmfaCfg)
+	endpointRegion, _s := setEndpointRegion(cliEndpointRegion, defaultEndpointRegion, profile, cred, cfg, awsmfaCfg, projectCfg)
 	source.endpointRegion = _s
 
+	result.setParam("duration_seconds", strconv.Itoa(int(durationSeconds)), source.durationSeconds)
+	result.setParam("mfa_serial", mfaSerial, source.mfaSerial)
+	result.setParam("endpoint_region", endpointRegion, source.endpointRegion)
+
+	// The MFA token code is input from the terminal. Fail fast before showing params if it is not available.
+	tty, err := openTerminal(ctx, cliTokenCodeTimeout)
+	if err != nil {
+		return err
+	}
+	defer tty.close()
+
 	// Show request params.
 	h, m, s := secToHMS(durationSeconds)
-	fmt.Printf("Try to get temporary token with following params ...\n")
-	table := tablewriter.NewWriter(os.Stdout)
+	if cliOutput != "none" {
+		fmt.Fprintf(humanOutput, "Try to get temporary token with following params ...\n")
+	}
+	table := tablewriter.NewWriter(humanOutput)
 	data := [][]string{}
 	if isSilent {
 		data = [][]string{
-			{"Profile to exec MFA", profile + beforeMFASuffix},
+			{"Profile to exec MFA", beforeMFAProfile},
+			{"Profile to save", outputProfile},
+			{"File to save", outputFile},
 			// {"Credentials", fmt.Sprintf("[Only for DEBUG] %+v", c.Credentials)},
 			{"Duration of token", fmt.Sprintf("%v sec (%vh %vm %vs)", durationSeconds, h, m, s)},
 			{"MFA device's serial", mfaSerial},
@@ -262,7 +495,9 @@ func handleGetSessionToken(profile string, cred *ini.File, cfg *ini.File, awsmfa
 		table.SetHeader([]string{"Parameter", "Value"})
 	} else {
 		data = [][]string{
-			{"Profile to exec MFA", profile + beforeMFASuffix, source.profile},
+			{"Profile to exec MFA", beforeMFAProfile, source.beforeMFAProfile},
+			{"Profile to save", outputProfile, source.outputProfile},
+			{"File to save", outputFile, source.outputFile},
 			// {"Credentials", fmt.Sprintf("[Only for DEBUG] %+v", c.Credentials)},
 			{"Duration of token", fmt.Sprintf("%v sec (%vh %vm %vs)", durationSeconds, h, m, s), source.durationSeconds},
 			{"MFA device's serial", mfaSerial, source.mfaSerial},
@@ -274,71 +509,150 @@ func handleGetSessionToken(profile string, cred *ini.File, cfg *ini.File, awsmfa
 	for _, v := range data {
 		table.Append(v)
 	}
-	table.Render()
+	if cliOutput != "none" {
+		table.Render()
+	}
 
-	// Get MFA token code from Stdin.
-	fmt.Print("Input your MFA token code: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	tokenCode := scanner.Text()
+	// Get MFA token code from the terminal.
+	tokenCode, err := askMFACode(tty.readSecret, tty.out, "Input your MFA token code", stateFilePath(), mfaSerial)
+	if err != nil {
+		return err
+	}
 
 	// Exec GetSessionToken API.
+	// If the MFA token code is rejected, ask a new one up to maxMFACodeAttempts times in total.
 	stsClient := sts.NewFromConfig(c)
-	token, err := stsClient.GetSessionToken(context.TODO(), &sts.GetSessionTokenInput{
-		DurationSeconds: &durationSeconds,
-		SerialNumber:    &mfaSerial,
-		TokenCode:       &tokenCode,
-	})
-	if err != nil {
-		return fmt.Errorf("something occured in calling AWS STS GetSessionToken API: %w", err)
+	var token *sts.GetSessionTokenOutput
+	for attempts := 1; ; attempts++ {
+		callCtx, cancel := withCallTimeout(ctx)
+		token, err = stsClient.GetSessionToken(callCtx, &sts.GetSessionTokenInput{
+			DurationSeconds: &durationSeconds,
+			SerialNumber:    &mfaSerial,
+			TokenCode:       &tokenCode,
+		})
+		cancel()
+		if err == nil {
+			break
+		}
+		if !isInvalidMFACodeError(err) || attempts >= maxMFACodeAttempts {
+			return classifySTSError(err, stsRequest{apiName: "GetSessionToken", profile: profile, beforeMFAProfile: beforeMFAProfile, mfaSerial: mfaSerial, endpointRegion: endpointRegion, durationSeconds: durationSeconds})
+		}
+
+		printYellow(fmt.Sprintf("[Warning] The MFA token code was rejected (attempt %v/%v). Wait for the next code.", attempts, maxMFACodeAttempts))
+		if tokenCode, err = askMFACode(tty.readSecret, tty.out, "Input your new MFA token code", stateFilePath(), mfaSerial); err != nil {
+			return err
+		}
+	}
+
+	// Never start to write any file if the run has been canceled, even after AWS STS has answered.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled before saving: %w", err)
+	}
+	// Record the used MFA token code, so that the next run doesn't waste a call of AWS STS with the same code.
+	if err := recordMFACode(stateFilePath(), mfaSerial, tokenCode, time.Now()); err != nil {
+		printBlue(fmt.Sprintf("[Tips] Failed to record the used MFA token code: %v\n", err))
 	}
 
 	// Add temporary token to the credentials file.
-	if err := saveTemporaryTokenFromGetSessionToken(token, profile, credentialsFilePath); err != nil {
+	// The credentials file may not exist yet if the before-mfa profile is defined only in the config file.
+	if err := createFileIfNotExist(outputFile, 0600); err != nil {
+		return fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	var metadata *sessionMetadata
+	if saveMetadata {
+		metadata = &sessionMetadata{
+			sourceProfile:  beforeMFAProfile,
+			mode:           "get-session-token",
+			mfaSerial:      mfaSerial,
+			issuedAt:       time.Now(),
+			endpointRegion: endpointRegion,
+		}
+	}
+	if err := saveTemporaryTokenFromGetSessionToken(ctx, token, outputProfile, outputFile, metadata); err != nil {
 		return fmt.Errorf("failed to save temporary credentials to file: %w", err)
 	}
 
-	printCyan(fmt.Sprintf("Success! New temporary credentials is saved as profile: %v\n", profile))
+	result.Outcome, result.Expiration = outcomeRefreshed, token.Credentials.Expiration
+	printCyan(fmt.Sprintf("Success! New temporary credentials is saved as profile: %v (%v)\n", outputProfile, outputFile))
 	return nil
 }
 
-func handleAssumeRole(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, source *source, isSilent bool) error {
+func handleAssumeRole(ctx context.Context, profile string, beforeMFAProfile string, outputProfile string, outputFile string, saveMetadata bool, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File, source *source, result *runResult, isSilent bool) error {
 	// Load long term credentials.
 	// To match the priority of credentials and config params (such as access_key) to aws's default order, including environment variables,
 	// awsmfa is sure to reload credentials and config file with aws-sdk-go-v2's build in loading config function before execute AssumeRole API.
-	c, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithSharedConfigProfile(profile+beforeMFASuffix),
+	loadCtx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	c, err := config.LoadDefaultConfig(loadCtx,
+		config.WithSharedConfigProfile(beforeMFAProfile),
+		config.WithSharedCredentialsFiles([]string{credentialsFilePath}),
+		config.WithSharedConfigFiles([]string{configFilePath}),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to load credentials: %w", err)
 	}
+	// Resolve the source credentials before asking MFA token code, so that a broken credential_process or sso session fails fast.
+	if _, err := c.Credentials.Retrieve(loadCtx); err != nil {
+		return fmt.Errorf("failed to resolve credentials of the profile \"%v\": %w", beforeMFAProfile, err)
+	}
 
 	// Set request params.
-	durationSeconds, _s := setDurationSeconds(cliDurationSeconds, defaultDurationSecondsAssumeRole, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
+	durationSeconds, _s, err := setDurationSeconds(cliDurationSeconds, defaultDurationSecondsAssumeRole, beforeMFAProfile, cred, cfg, awsmfaCfg)
 	source.durationSeconds = _s
-	mfaSerial, _s, err := setMFASerial(cliMfaSerial, defaultMFASerial, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
+	if err != nil {
+		return err
+	}
+	if clamped, ok := clampDurationSeconds(durationSeconds, "assume-role"); !ok {
+		min, max := durationSecondsLimits("assume-role")
+		printYellow(fmt.Sprintf("[Warning] The duration %v sec is out of the range of AssumeRole (%v - %v sec). It is clamped to %v sec.", durationSeconds, min, max, clamped))
+		durationSeconds = clamped
+	}
+	mfaSerial, _s, err := setMFASerial(cliMfaSerial, defaultMFASerial, beforeMFAProfile, cred, cfg, awsmfaCfg)
 	source.mfaSerial = _s
 	if err != nil {
 		return fmt.Errorf("The mfa_serial is not specified. You can set it in %v, %v, %v or --serial-number", credentialsFilePath, configFilePath, awsmfaCfgFilePath)
 	}
-	endpointRegion, _s := setEndpointRegion(cliEndpointRegion, defaultEndpointRegion, profile, cred, cfg, awsmfaCfg)
+	endpointRegion, _s := setEndpointRegion(cliEndpointRegion, defaultEndpointRegion, profile, cred, cfg, awsmfaCfg, projectCfg)
 	source.endpointRegion = _s
-	roleArn, _s, err := setRoleArn(cliRoleArn, profile+beforeMFASuffix, cred, cfg)
+	roleArn, _s, err := setRoleArn(cliRoleArn, beforeMFAProfile, cred, cfg, projectCfg)
 	source.roleArn = _s
 	if err != nil {
 		return fmt.Errorf("The role_arn is not specified. You can set it in %v, %v or --role-arn", credentialsFilePath, configFilePath)
 	}
-	roleSessionName, _s := setRoleSessionName(cliRoleSessionName, defaultRoleSessionName, profile+beforeMFASuffix, cred, cfg, awsmfaCfg)
+	roleSessionName, _s := setRoleSessionName(cliRoleSessionName, defaultRoleSessionName, beforeMFAProfile, cred, cfg, awsmfaCfg)
 	source.roleSessionName = _s
+	// Shorten the duration only if AWS STS has rejected it for the role recently, so that the MFA token code is not wasted.
+	// A duration between the accepted and the rejected one is sent as it is, so that the longest accepted duration grows.
+	if limit, ok := loadRoleLimit(roleLimitsFilePath(), roleArn, time.Now()); ok && limit.rejected > 0 && durationSeconds >= limit.rejected {
+		printYellow(fmt.Sprintf("[Warning] AWS STS rejected %v sec for the role at %v. The duration %v sec is shortened to %v sec, which was accepted. If you have extended the max session duration, run 'awsmfa role-limits clear %v'.", limit.rejected, limit.checkedAt.Local().Format(time.RFC3339), durationSeconds, limit.accepted, roleArn))
+		durationSeconds = limit.accepted
+	}
+
+	result.setParam("duration_seconds", strconv.Itoa(int(durationSeconds)), source.durationSeconds)
+	result.setParam("mfa_serial", mfaSerial, source.mfaSerial)
+	result.setParam("endpoint_region", endpointRegion, source.endpointRegion)
+	result.setParam("role_arn", roleArn, source.roleArn)
+	result.setParam("role_session_name", roleSessionName, source.roleSessionName)
+
+	// The MFA token code is input from the terminal. Fail fast before showing params if it is not available.
+	tty, err := openTerminal(ctx, cliTokenCodeTimeout)
+	if err != nil {
+		return err
+	}
+	defer tty.close()
 
 	// Show request params.
 	h, m, s := secToHMS(durationSeconds)
-	fmt.Printf("Try to get temporary token with following params ...\n")
-	table := tablewriter.NewWriter(os.Stdout)
+	if cliOutput != "none" {
+		fmt.Fprintf(humanOutput, "Try to get temporary token with following params ...\n")
+	}
+	table := tablewriter.NewWriter(humanOutput)
 	data := [][]string{}
 	if isSilent {
 		data = [][]string{
-			{"Profile to exec MFA", profile + beforeMFASuffix},
+			{"Profile to exec MFA", beforeMFAProfile},
+			{"Profile to save", outputProfile},
+			{"File to save", outputFile},
 			// {"Credentials", fmt.Sprintf("[Only for DEBUG] %+v", c.Credentials)},
 			{"Role arn to assume", fmt.Sprintf("%v", roleArn)},
 			{"Role session name", fmt.Sprintf("%v", roleSessionName)},
@@ -350,7 +664,9 @@ func handleAssumeRole(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *
 		table.SetHeader([]string{"Parameter", "Value"})
 	} else {
 		data = [][]string{
-			{"Profile to exec MFA", profile + beforeMFASuffix, source.profile},
+			{"Profile to exec MFA", beforeMFAProfile, source.beforeMFAProfile},
+			{"Profile to save", outputProfile, source.outputProfile},
+			{"File to save", outputFile, source.outputFile},
 			// {"Credentials", fmt.Sprintf("[Only for DEBUG] %+v", c.Credentials)},
 			{"Role arn to assume", fmt.Sprintf("%v", roleArn), source.roleArn},
 			{"Role session name", fmt.Sprintf("%v", roleSessionName), source.roleSessionName},
@@ -364,48 +680,171 @@ func handleAssumeRole(profile string, cred *ini.File, cfg *ini.File, awsmfaCfg *
 	for _, v := range data {
 		table.Append(v)
 	}
-	table.Render()
+	if cliOutput != "none" {
+		table.Render()
+	}
 
-	// Get MFA token code from Stdin.
-	fmt.Print("Input your MFA token code: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	tokenCode := scanner.Text()
+	// Get MFA token code from the terminal.
+	tokenCode, err := askMFACode(tty.readSecret, tty.out, "Input your MFA token code", stateFilePath(), mfaSerial)
+	if err != nil {
+		return err
+	}
 
 	// Exec AssumeRole API.
+	// If the MFA token code is rejected, ask a new one up to maxMFACodeAttempts times in total.
+	// If the duration exceeds the max session duration of the role, retry with a shorter duration and a new MFA token code.
 	stsClient := sts.NewFromConfig(c)
-	token, err := stsClient.AssumeRole(context.TODO(), &sts.AssumeRoleInput{
-		DurationSeconds: &durationSeconds,
-		SerialNumber:    &mfaSerial,
-		RoleArn:         &roleArn,
-		RoleSessionName: &roleSessionName,
-		TokenCode:       &tokenCode,
-	})
-	if err != nil {
-		return fmt.Errorf("something occured in calling AWS STS AssumeRole API: %w", err)
+	var token *sts.AssumeRoleOutput
+	var rejected int32
+	for attempts := 1; ; {
+		callCtx, cancel := withCallTimeout(ctx)
+		token, err = stsClient.AssumeRole(callCtx, &sts.AssumeRoleInput{
+			DurationSeconds: &durationSeconds,
+			SerialNumber:    &mfaSerial,
+			RoleArn:         &roleArn,
+			RoleSessionName: &roleSessionName,
+			TokenCode:       &tokenCode,
+		})
+		cancel()
+		if err == nil {
+			break
+		}
+
+		shorter, ok := shorterDurationSeconds(durationSeconds)
+		switch {
+		case isDurationTooLongError(err) && ok:
+			printYellow(fmt.Sprintf("[Warning] The duration %v sec exceeds the max session duration of the role %v.", durationSeconds, roleArn))
+			answer, err := askValue(tty.reader, tty.out, "Input a shorter duration to retry, or 'n' to abort", humanDuration(time.Duration(shorter)*time.Second), shorterDurationValidator(durationSeconds))
+			if err != nil {
+				return err
+			}
+			if answer == "n" {
+				return fmt.Errorf("the duration %v sec exceeds the max session duration of the role %v. Please specify a shorter duration by --duration-seconds", durationSeconds, roleArn)
+			}
+			rejected = durationSeconds
+			durationSeconds, _ = parseDurationSeconds(answer)
+		case isInvalidMFACodeError(err) && attempts < maxMFACodeAttempts:
+			printYellow(fmt.Sprintf("[Warning] The MFA token code was rejected (attempt %v/%v). Wait for the next code.", attempts, maxMFACodeAttempts))
+			attempts++
+		default:
+			return classifySTSError(err, stsRequest{apiName: "AssumeRole", profile: profile, beforeMFAProfile: beforeMFAProfile, mfaSerial: mfaSerial, roleArn: roleArn, endpointRegion: endpointRegion, durationSeconds: durationSeconds})
+		}
+
+		if tokenCode, err = askMFACode(tty.readSecret, tty.out, "Input your new MFA token code", stateFilePath(), mfaSerial); err != nil {
+			return err
+		}
+	}
+	// Never start to write any file if the run has been canceled, even after AWS STS has answered.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled before saving: %w", err)
+	}
+	// Record the used MFA token code, so that the next run doesn't waste a call of AWS STS with the same code.
+	if err := recordMFACode(stateFilePath(), mfaSerial, tokenCode, time.Now()); err != nil {
+		printBlue(fmt.Sprintf("[Tips] Failed to record the used MFA token code: %v\n", err))
+	}
+
+	// Remember the rejected and the accepted duration, so that the next run doesn't exceed the max session duration of the role.
+	if err := recordRoleLimit(roleLimitsFilePath(), roleArn, rejected, durationSeconds, time.Now()); err != nil {
+		printBlue(fmt.Sprintf("[Tips] Failed to remember the max session duration of the role: %v\n", err))
 	}
 
 	// Add temporary token to the credentials file.
-	if err := saveTemporaryTokenFromAssumeRole(token, profile, credentialsFilePath); err != nil {
+	// The credentials file may not exist yet if the before-mfa profile is defined only in the config file.
+	if err := createFileIfNotExist(outputFile, 0600); err != nil {
+		return fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	var metadata *sessionMetadata
+	if saveMetadata {
+		metadata = &sessionMetadata{
+			sourceProfile:    beforeMFAProfile,
+			mode:             "assume-role",
+			roleArn:          roleArn,
+			roleSessionName:  roleSessionName,
+			mfaSerial:        mfaSerial,
+			issuedAt:         time.Now(),
+			endpointRegion:   endpointRegion,
+			packedPolicySize: token.PackedPolicySize,
+		}
+		if token.AssumedRoleUser != nil && token.AssumedRoleUser.Arn != nil {
+			metadata.assumedRoleUserArn = *token.AssumedRoleUser.Arn
+		}
+	}
+	if err := saveTemporaryTokenFromAssumeRole(ctx, token, outputProfile, outputFile, metadata); err != nil {
 		return fmt.Errorf("failed to save temporary credentials to file: %w", err)
 	}
 
-	printCyan(fmt.Sprintf("Success! New temporary credentials is saved as profile: %v\n", profile))
+	// The duration may be shortened by the max session duration of the role.
+	result.setParam("duration_seconds", strconv.Itoa(int(durationSeconds)), source.durationSeconds)
+	result.Outcome, result.Expiration = outcomeRefreshed, token.Credentials.Expiration
+	printCyan(fmt.Sprintf("Success! New temporary credentials is saved as profile: %v (%v)\n", outputProfile, outputFile))
 	return nil
 }
 
-// hasActiveToken checks if the specified profile has an active token.
-func hasActiveToken(profile string, cred *ini.File) (hasActiveToken bool, due *time.Time) {
-	if sec, err := cred.GetSection(profile); err == nil {
-		if tokenDue, err := sec.Key("expiration").TimeFormat(time.RFC3339); err == nil {
-			if !isExpired(tokenDue, time.Now().UTC()) {
-				return true, &tokenDue
-			}
+// withCallTimeout returns the context of a call to AWS, which is canceled after --timeout.
+func withCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cliTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cliTimeout)
+}
+
+// hasActiveToken checks if the specified profile has an active token which remains at least minRemaining.
+func hasActiveToken(profile string, cred *ini.File, minRemaining time.Duration) (hasActiveToken bool, due *time.Time) {
+	if tokenDue, ok := tokenExpiration(profile, cred); ok {
+		if !isExpired(*tokenDue, time.Now().UTC().Add(minRemaining)) {
+			return true, tokenDue
 		}
 	}
 	return false, nil
 }
 
+// tokenExpiration returns the expiration of the temporary token of the profile, whether it is expired or not.
+func tokenExpiration(profile string, cred *ini.File) (due *time.Time, ok bool) {
+	sec, err := cred.GetSection(profile)
+	if err != nil {
+		return nil, false
+	}
+	tokenDue, err := sec.Key("expiration").TimeFormat(time.RFC3339)
+	if err != nil {
+		return nil, false
+	}
+	return &tokenDue, true
+}
+
+// credentialKeys are keys from which aws-sdk-go-v2 resolves the source credentials of a profile.
+// The order is same as the priority of aws-sdk-go-v2.
+var credentialKeys = []string{"source_profile", "aws_access_key_id", "credential_source", "sso_start_url", "credential_process"}
+
+// credentialSourceOf returns how aws-sdk-go-v2 resolves the source credentials of the profile.
+// It only reads the loaded files and environment variables, and never runs credential_process or calls any API.
+func credentialSourceOf(profile string, cred *ini.File, cfg *ini.File) (source string, err error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
+		return "env AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", nil
+	}
+
+	sections := []struct {
+		name   string
+		source paramSource
+		file   *ini.File
+	}{
+		{name: profile, source: SharedCredentials, file: cred},
+		{name: "profile " + profile, source: SharedConfig, file: cfg},
+	}
+	for _, k := range credentialKeys {
+		for _, s := range sections {
+			sec, err := s.file.GetSection(s.name)
+			if err != nil || !sec.HasKey(k) || sec.Key(k).String() == "" {
+				continue
+			}
+			if k == "aws_access_key_id" && sec.Key("aws_secret_access_key").String() == "" {
+				continue
+			}
+			return fmt.Sprintf("%v in [%v] of %v", k, s.name, s.source), nil
+		}
+	}
+	return "", fmt.Errorf("The profile \"%v\" has no way to resolve credentials. Please set aws_access_key_id and aws_secret_access_key, credential_process, sso_start_url or source_profile to [%v] in %v or [profile %v] in %v", profile, profile, credentialsFilePath, profile, configFilePath)
+}
+
 // isExpired checks if a temporary token is expired.
 func isExpired(tokenDue time.Time, comparison time.Time) bool {
 	if comparison.After(tokenDue) {
@@ -414,6 +853,30 @@ func isExpired(tokenDue time.Time, comparison time.Time) bool {
 	return false
 }
 
+// humanDurationMax is the longest duration which humanDuration prints as it is.
+const humanDurationMax = 365 * 24 * time.Hour
+
+// humanDuration formats a duration such as 3d4h, 2h13m, 45m or 30s. A duration of 2 days or longer is in days.
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%vs", int(d.Seconds()))
+	}
+	// A far future expiration, such as of a hand written profile, saturates time.Duration, so it is capped.
+	if d > humanDurationMax {
+		return fmt.Sprintf(">%vd", int(humanDurationMax.Hours())/24)
+	}
+	if d >= 48*time.Hour {
+		d = d.Truncate(time.Hour)
+		return fmt.Sprintf("%vd%vh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+	d = d.Truncate(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h == 0 {
+		return fmt.Sprintf("%vm", m)
+	}
+	return fmt.Sprintf("%vh%vm", h, m)
+}
+
 // secToHMS convert seconds to hour, min and sec.
 func secToHMS(seconds int32) (hour int32, min int32, sec int32) {
 	h := seconds / 3600
@@ -423,8 +886,37 @@ func secToHMS(seconds int32) (hour int32, min int32, sec int32) {
 	return h, m, s
 }
 
+// managedMarkerKey is a key written on every profile awsmfa saves, to tell it from profiles which users manage.
+const managedMarkerKey = "awsmfa_managed"
+
+// checkOverwritable checks if the profile can be overwritten with temporary credentials.
+// A profile which has access keys without a session token is regarded as long term credentials managed by the user,
+// unless it has the awsmfa_managed marker.
+func checkOverwritable(profile string, cred *ini.File) error {
+	sec, err := cred.GetSection(profile)
+	if err != nil {
+		return nil
+	}
+	if sec.HasKey(managedMarkerKey) || sec.HasKey("aws_session_token") || !sec.HasKey("aws_access_key_id") {
+		return nil
+	}
+	return fmt.Errorf("The profile \"%v\" holds long term credentials which are not managed by awsmfa. awsmfa refuses to overwrite them", profile)
+}
+
+// createFileIfNotExist creates an empty file and its directory if the file doesn't exist.
+func createFileIfNotExist(path string, perm os.FileMode) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileWithDir(path, []byte{}, perm)
+}
+
 // saveTemporaryTokenFromGetSessionToken writes credentials to a shared credentials file.
-func saveTemporaryTokenFromGetSessionToken(token *sts.GetSessionTokenOutput, profile string, credentialsFilePath string) error {
+func saveTemporaryTokenFromGetSessionToken(ctx context.Context, token *sts.GetSessionTokenOutput, profile string, credentialsFilePath string, metadata *sessionMetadata) error {
+	// Never start to save if the run has been canceled.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled before saving: %w", err)
+	}
 	cred, err := ini.Load(credentialsFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load credentials file: %w", err)
@@ -433,8 +925,10 @@ func saveTemporaryTokenFromGetSessionToken(token *sts.GetSessionTokenOutput, pro
 	cred.Section(profile).Key("aws_secret_access_key").SetValue(*token.Credentials.SecretAccessKey)
 	cred.Section(profile).Key("aws_session_token").SetValue(*token.Credentials.SessionToken)
 	cred.Section(profile).Key("expiration").SetValue(token.Credentials.Expiration.Format(time.RFC3339))
+	cred.Section(profile).Key(managedMarkerKey).SetValue("true")
+	writeMetadata(cred.Section(profile), metadata)
 
-	if err := cred.SaveTo(credentialsFilePath); err != nil {
+	if err := saveIniAtomic(cred, credentialsFilePath); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
 	}
 
@@ -442,7 +936,11 @@ func saveTemporaryTokenFromGetSessionToken(token *sts.GetSessionTokenOutput, pro
 }
 
 // saveTemporaryTokenFromAssumeRole writes credentials to a shared credentials file.
-func saveTemporaryTokenFromAssumeRole(token *sts.AssumeRoleOutput, profile string, credentialsFilePath string) error {
+func saveTemporaryTokenFromAssumeRole(ctx context.Context, token *sts.AssumeRoleOutput, profile string, credentialsFilePath string, metadata *sessionMetadata) error {
+	// Never start to save if the run has been canceled.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled before saving: %w", err)
+	}
 	cred, err := ini.Load(credentialsFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to load credentials file: %w", err)
@@ -451,8 +949,10 @@ func saveTemporaryTokenFromAssumeRole(token *sts.AssumeRoleOutput, profile strin
 	cred.Section(profile).Key("aws_secret_access_key").SetValue(*token.Credentials.SecretAccessKey)
 	cred.Section(profile).Key("aws_session_token").SetValue(*token.Credentials.SessionToken)
 	cred.Section(profile).Key("expiration").SetValue(token.Credentials.Expiration.Format(time.RFC3339))
+	cred.Section(profile).Key(managedMarkerKey).SetValue("true")
+	writeMetadata(cred.Section(profile), metadata)
 
-	if err := cred.SaveTo(credentialsFilePath); err != nil {
+	if err := saveIniAtomic(cred, credentialsFilePath); err != nil {
 		return fmt.Errorf("failed to save: %w", err)
 	}
 	return nil
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/spf13/cobra"
 	"gopkg.in/ini.v1"
 )
 
@@ -56,7 +58,7 @@ func Test_hasActiveToken(t *testing.T) {
 				t.Errorf("failed to load test data: %v", tt.testDataPath)
 			}
 
-			gotHasActiveToken, _ := hasActiveToken(tt.args.profile, cred)
+			gotHasActiveToken, _ := hasActiveToken(tt.args.profile, cred, 0)
 			if gotHasActiveToken != tt.wantHasActiveToken {
 				t.Errorf("hasActiveToken() gotHasActiveToken = %v, want %v", gotHasActiveToken, tt.wantHasActiveToken)
 			}
@@ -67,6 +69,42 @@ func Test_hasActiveToken(t *testing.T) {
 	}
 }
 
+func Test_hasActiveToken_minRemaining(t *testing.T) {
+	expiration := time.Now().UTC().Add(5 * time.Minute).Format(time.RFC3339)
+	cred, err := ini.Load([]byte("[soon]\nexpiration = " + expiration + "\n"))
+	if err != nil {
+		t.Fatalf("failed to load test data: %v", err)
+	}
+
+	if got, _ := hasActiveToken("soon", cred, time.Minute); got != true {
+		t.Errorf("hasActiveToken() = %v, want true", got)
+	}
+	if got, _ := hasActiveToken("soon", cred, 10*time.Minute); got != false {
+		t.Errorf("hasActiveToken() = %v, want false", got)
+	}
+}
+
+func Test_humanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 2*time.Hour + 13*time.Minute + 30*time.Second, want: "2h13m"},
+		{d: 45 * time.Minute, want: "45m"},
+		{d: 30 * time.Second, want: "30s"},
+		{d: 36 * time.Hour, want: "36h0m"},
+		{d: 76*time.Hour + 30*time.Minute, want: "3d4h"},
+		{d: humanDurationMax, want: "365d0h"},
+		{d: humanDurationMax + time.Hour, want: ">365d"},
+		{d: time.Date(2999, 11, 23, 14, 15, 16, 0, time.UTC).Sub(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)), want: ">365d"},
+	}
+	for _, tt := range tests {
+		if got := humanDuration(tt.d); got != tt.want {
+			t.Errorf("humanDuration(%v) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
 func Test_saveTemporaryTokenFromGetSessionToken(t *testing.T) {
 	type args struct {
 		token               *sts.GetSessionTokenOutput
@@ -107,7 +145,7 @@ func Test_saveTemporaryTokenFromGetSessionToken(t *testing.T) {
 				}
 				defer backup.SaveTo(tt.fileToRestore)
 
-				if err := saveTemporaryTokenFromGetSessionToken(tt.args.token, tt.args.profile, tt.args.credentialsFilePath); (err != nil) != tt.wantErr {
+				if err := saveTemporaryTokenFromGetSessionToken(context.Background(), tt.args.token, tt.args.profile, tt.args.credentialsFilePath, nil); (err != nil) != tt.wantErr {
 					t.Errorf("saveTemporaryTokenFromGetSessionToken() error = %v, wantErr %v", err, tt.wantErr)
 				}
 
@@ -148,7 +186,7 @@ func Test_saveTemporaryTokenFromGetSessionToken(t *testing.T) {
 				}
 				defer backup.SaveTo(tt.realCredentialsFilePath)
 
-				if err := saveTemporaryTokenFromGetSessionToken(tt.args.token, tt.args.profile, tt.args.credentialsFilePath); (err != nil) != tt.wantErr {
+				if err := saveTemporaryTokenFromGetSessionToken(context.Background(), tt.args.token, tt.args.profile, tt.args.credentialsFilePath, nil); (err != nil) != tt.wantErr {
 					t.Errorf("saveTemporaryTokenFromGetSessionToken() error = %v, wantErr %v", err, tt.wantErr)
 				}
 
@@ -210,7 +248,7 @@ func Test_saveTemporaryTokenFromAssumeRole(t *testing.T) {
 				}
 				defer backup.SaveTo(tt.fileToRestore)
 
-				if err := saveTemporaryTokenFromAssumeRole(tt.args.token, tt.args.profile, tt.args.credentialsFilePath); (err != nil) != tt.wantErr {
+				if err := saveTemporaryTokenFromAssumeRole(context.Background(), tt.args.token, tt.args.profile, tt.args.credentialsFilePath, nil); (err != nil) != tt.wantErr {
 					t.Errorf("saveTemporaryTokenFromAssumeRole() error = %v, wantErr %v", err, tt.wantErr)
 				}
 
@@ -251,7 +289,7 @@ func Test_saveTemporaryTokenFromAssumeRole(t *testing.T) {
 				}
 				defer backup.SaveTo(tt.realCredentialsFilePath)
 
-				if err := saveTemporaryTokenFromAssumeRole(tt.args.token, tt.args.profile, tt.args.credentialsFilePath); (err != nil) != tt.wantErr {
+				if err := saveTemporaryTokenFromAssumeRole(context.Background(), tt.args.token, tt.args.profile, tt.args.credentialsFilePath, nil); (err != nil) != tt.wantErr {
 					t.Errorf("saveTemporaryTokenFromAssumeRole() error = %v, wantErr %v", err, tt.wantErr)
 				}
 
@@ -389,3 +427,97 @@ func Test_secToHMS(t *testing.T) {
 		})
 	}
 }
+
+func Test_credentialSourceOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   string
+		existsEnv bool
+		wantErr   bool
+	}{
+		{name: "S01: long term access keys", profile: "ok-before-mfa", existsEnv: false, wantErr: false},
+		{name: "S02: credential_process", profile: "process-before-mfa", existsEnv: false, wantErr: false},
+		{name: "S03: environment variables", profile: "nothing-before-mfa", existsEnv: true, wantErr: false},
+		{name: "F01: no secret access key", profile: "nokey-before-mfa", existsEnv: false, wantErr: true},
+		{name: "F02: no profile", profile: "nothing-before-mfa", existsEnv: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+			defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+			if tt.existsEnv {
+				os.Setenv("AWS_ACCESS_KEY_ID", "env")
+				os.Setenv("AWS_SECRET_ACCESS_KEY", "env")
+			}
+			cred, err := ini.Load("testdata/doctor_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+			cfg, err := ini.Load("testdata/doctor_config")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			if _, err := credentialSourceOf(tt.profile, cred, cfg); (err != nil) != tt.wantErr {
+				t.Errorf("credentialSourceOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkOverwritable(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		wantErr bool
+	}{
+		{name: "S01: temporary credentials", profile: "temporary", wantErr: false},
+		{name: "S02: managed by awsmfa", profile: "managed", wantErr: false},
+		{name: "S03: no access key", profile: "region-only", wantErr: false},
+		{name: "S04: new profile", profile: "new", wantErr: false},
+		{name: "F01: long term credentials", profile: "long-term", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := ini.Load("testdata/checkOverwritable_credentials")
+			if err != nil {
+				t.Errorf("failed to load test data: %v", err)
+			}
+
+			if err := checkOverwritable(tt.profile, cred); (err != nil) != tt.wantErr {
+				t.Errorf("checkOverwritable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_refreshCredentials_active(t *testing.T) {
+	defer func(cred, cfg, awsmfaCfg, profile string) {
+		credentialsFilePath, configFilePath, awsmfaCfgFilePath, cliProfile = cred, cfg, awsmfaCfg, profile
+	}(credentialsFilePath, configFilePath, awsmfaCfgFilePath, cliProfile)
+	credentialsFilePath = "testdata/refreshCredentials_credentials"
+	configFilePath = "testdata/missing_config"
+	awsmfaCfgFilePath = "testdata/missing_configuration"
+	cliProfile = "active"
+
+	result := newRunResult()
+	cmd := &cobra.Command{
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return refreshCredentials(cmd, result)
+		},
+	}
+	cmd.Flags().Bool("force", false, "")
+	cmd.Flags().Bool("silent", false, "")
+	cmd.SetArgs([]string{})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("refreshCredentials() error = %v", err)
+	}
+
+	// The result of an active token has the action mode as well as a refreshed one.
+	if result.Outcome != outcomeActive || result.Mode != "assume-role" {
+		t.Errorf("refreshCredentials() outcome = %v, mode = %v, want %v, assume-role", result.Outcome, result.Mode, outcomeActive)
+	}
+	if p := result.Params["refresh_threshold"]; p.Value != "0s" || p.Source != AwsmfaBuildIn.String() {
+		t.Errorf("refreshCredentials() refresh_threshold = %+v, want 0s from %v", p, AwsmfaBuildIn.String())
+	}
+}
@@ -76,9 +76,16 @@ suffix_of_before_mfa_profile       = -before-mfa
 mode                               = get-session-token
 profile                            = default
 # mfa_serial                       = YOUR_SERIAL_HERE!!!
-endpoint_region                    = aws_global
-duration_seconds_get_session_token = 43200
-duration_seconds_assume_role       = 3600
+region                             = aws_global
+# duration_seconds                 = 43200
+# role_session_name                = awsmfa-session
+# destination_profile_template     = {profile}-mfa
+# save_metadata                    = false
+# refresh_threshold                = 10m
+
+# Map before-mfa profiles to the profiles where temporary credentials are saved.
+# [profile-mapping]
+# YOUR_BEFORE_MFA_PROFILE_HERE!!! = YOUR_PROFILE_HERE!!!
 `
 
 	p := dir + "/" + file
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// stsErrorKind is a kind of the errors returned by AWS STS API.
+type stsErrorKind int
+
+const (
+	_ stsErrorKind = iota
+	stsErrorInvalidMFACode
+	stsErrorClockSkew
+	stsErrorAccessDenied
+	stsErrorInvalidCredentials
+	stsErrorRegionDisabled
+	stsErrorDurationTooLong
+	stsErrorThrottling
+	stsErrorTimeout
+)
+
+// Exit codes of awsmfa. Errors of AWS STS API have a distinct exit code per kind so that scripts can handle them.
+const (
+	exitError              = 1
+	exitInvalidMFACode     = 10
+	exitClockSkew          = 11
+	exitAccessDenied       = 12
+	exitInvalidCredentials = 13
+	exitRegionDisabled     = 14
+	exitDurationTooLong    = 15
+	exitThrottling         = 16
+	exitTimeout            = 17
+	exitCanceled           = 130
+)
+
+func (k stsErrorKind) exitCode() int {
+	switch k {
+	case stsErrorInvalidMFACode:
+		return exitInvalidMFACode
+	case stsErrorClockSkew:
+		return exitClockSkew
+	case stsErrorAccessDenied:
+		return exitAccessDenied
+	case stsErrorInvalidCredentials:
+		return exitInvalidCredentials
+	case stsErrorRegionDisabled:
+		return exitRegionDisabled
+	case stsErrorDurationTooLong:
+		return exitDurationTooLong
+	case stsErrorThrottling:
+		return exitThrottling
+	case stsErrorTimeout:
+		return exitTimeout
+	default:
+		return exitError
+	}
+}
+
+// stsRequest is the request params which are referred in the messages of AWS STS API errors.
+type stsRequest struct {
+	apiName          string
+	profile          string
+	beforeMFAProfile string
+	mfaSerial        string
+	roleArn          string
+	endpointRegion   string
+	durationSeconds  int32
+}
+
+// stsError is an error of AWS STS API with a message and a fix for the user.
+type stsError struct {
+	kind    stsErrorKind
+	message string
+	fix     string
+	err     error
+}
+
+func (e *stsError) Error() string {
+	return fmt.Sprintf("%v\n[Fix] %v\n[Detail] %v", e.message, e.fix, e.err)
+}
+
+func (e *stsError) Unwrap() error {
+	return e.err
+}
+
+// exitCodeOf returns the exit code of the error.
+// A run canceled by Ctrl-C exits with 130 like shells.
+func exitCodeOf(err error) int {
+	if errors.Is(err, errInputCanceled) || errors.Is(err, context.Canceled) {
+		return exitCanceled
+	}
+	var se *stsError
+	if errors.As(err, &se) {
+		return se.kind.exitCode()
+	}
+	return exitError
+}
+
+// isInvalidMFACodeError reports whether AWS STS rejected the MFA token code.
+func isInvalidMFACodeError(err error) bool {
+	var se *stsError
+	return errors.As(classifySTSError(err, stsRequest{}), &se) && se.kind == stsErrorInvalidMFACode
+}
+
+// classifySTSError converts an error of AWS STS API into a stsError with a tailored message and a fix.
+// An error which is not classified is returned with the name of the API.
+func classifySTSError(err error, req stsRequest) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &stsError{
+			kind:    stsErrorTimeout,
+			message: fmt.Sprintf("AWS STS %v API didn't respond in %v.", req.apiName, cliTimeout),
+			fix:     fmt.Sprintf("Check the network to AWS STS in the region %v, such as proxy settings, or extend --timeout.", req.endpointRegion),
+			err:     err,
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("canceled in calling AWS STS %v API: %w", req.apiName, err)
+	}
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return fmt.Errorf("something occured in calling AWS STS %v API: %w", req.apiName, err)
+	}
+	code, msg := ae.ErrorCode(), ae.ErrorMessage()
+
+	switch {
+	case code == "AccessDenied" && strings.Contains(msg, "MultiFactorAuthentication"):
+		return &stsError{
+			kind:    stsErrorInvalidMFACode,
+			message: "The MFA token code was rejected. It is wrong, already used, or not for the MFA device.",
+			fix:     fmt.Sprintf("Wait for the next code of the MFA device and try again. If it fails again, check mfa_serial of the profile \"%v\" (now %v).", req.beforeMFAProfile, req.mfaSerial),
+			err:     err,
+		}
+	case code == "RequestExpired" || (code == "SignatureDoesNotMatch" && strings.Contains(msg, "Signature expired")):
+		return &stsError{
+			kind:    stsErrorClockSkew,
+			message: "The request was rejected because the clock of this machine is out of sync.",
+			fix:     "Synchronize the clock of this machine (such as by NTP). TOTP codes of the MFA device also depend on the clock.",
+			err:     err,
+		}
+	case code == "AccessDenied":
+		fix := fmt.Sprintf("Check that the IAM user of the profile \"%v\" is allowed to call sts:%v.", req.beforeMFAProfile, req.apiName)
+		if req.roleArn != "" {
+			fix = fmt.Sprintf("Check that the trust policy of the role %v (awsmfa_role_arn of the profile \"%v\") trusts the IAM user with MFA, and the IAM user is allowed to call sts:AssumeRole on it.", req.roleArn, req.beforeMFAProfile)
+		}
+		return &stsError{kind: stsErrorAccessDenied, message: "The request was denied by AWS.", fix: fix, err: err}
+	case code == "InvalidClientTokenId" || code == "ExpiredToken" || code == "SignatureDoesNotMatch" || code == "UnrecognizedClientException":
+		return &stsError{
+			kind:    stsErrorInvalidCredentials,
+			message: "The long term credentials are invalid, expired or deactivated.",
+			fix:     fmt.Sprintf("Check aws_access_key_id and aws_secret_access_key (or the credential source) of the profile \"%v\" in %v. The profile should hold long term credentials, not temporary ones.", req.beforeMFAProfile, credentialsFilePath),
+			err:     err,
+		}
+	case code == "RegionDisabledException":
+		return &stsError{
+			kind:    stsErrorRegionDisabled,
+			message: fmt.Sprintf("AWS STS is not activated in the region %v for the account.", req.endpointRegion),
+			fix:     fmt.Sprintf("Activate the region in Account settings of IAM console, or change region of the profile \"%v\" (or AWSMFA_ENDPOINT_REGION, --endpoint-region) to an active region such as aws_global.", req.beforeMFAProfile),
+			err:     err,
+		}
+	case code == "ValidationError" && (strings.Contains(msg, "MaxSessionDuration") || strings.Contains(msg, "durationSeconds")):
+		return &stsError{
+			kind:    stsErrorDurationTooLong,
+			message: fmt.Sprintf("The duration %v sec is not accepted by AWS STS %v API.", req.durationSeconds, req.apiName),
+			fix:     fmt.Sprintf("Shorten duration_seconds of the profile \"%v\" (or --duration-seconds). The max session duration of the role is set in IAM.", req.beforeMFAProfile),
+			err:     err,
+		}
+	case code == "Throttling" || code == "ThrottlingException" || code == "RequestLimitExceeded":
+		return &stsError{
+			kind:    stsErrorThrottling,
+			message: "The request was throttled by AWS STS.",
+			fix:     "Wait a moment and try again. Other tools which call AWS STS with the same account may be running.",
+			err:     err,
+		}
+	}
+	return fmt.Errorf("something occured in calling AWS STS %v API: %w", req.apiName, err)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func Test_classifySTSError(t *testing.T) {
+	req := stsRequest{apiName: "AssumeRole", profile: "sample", beforeMFAProfile: "sample-before-mfa", mfaSerial: "arn:aws:iam::123456789012:mfa/user", endpointRegion: "ap-northeast-1", durationSeconds: 43200}
+	tests := []struct {
+		name         string
+		err          error
+		req          stsRequest
+		wantExitCode int
+		wantFix      string
+	}{
+		{name: "S01: invalid MFA code", err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "MultiFactorAuthentication failed with invalid MFA one time pass code."}, req: req, wantExitCode: exitInvalidMFACode, wantFix: "mfa_serial of the profile \"sample-before-mfa\""},
+		{name: "S02: clock skew", err: &smithy.GenericAPIError{Code: "SignatureDoesNotMatch", Message: "Signature expired: 20261016T100000Z is now earlier than 20261016T100500Z"}, req: req, wantExitCode: exitClockSkew, wantFix: "clock"},
+		{name: "S03: access denied by trust policy", err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "User: arn:aws:iam::123456789012:user/user is not authorized to perform: sts:AssumeRole"}, req: stsRequest{apiName: "AssumeRole", beforeMFAProfile: "sample-before-mfa", roleArn: "arn:aws:iam::123456789012:role/admin"}, wantExitCode: exitAccessDenied, wantFix: "trust policy of the role arn:aws:iam::123456789012:role/admin (awsmfa_role_arn of the profile \"sample-before-mfa\")"},
+		{name: "S04: access denied without role", err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}, req: stsRequest{apiName: "GetSessionToken", beforeMFAProfile: "sample-before-mfa"}, wantExitCode: exitAccessDenied, wantFix: "sts:GetSessionToken"},
+		{name: "S05: invalid key", err: &smithy.GenericAPIError{Code: "InvalidClientTokenId", Message: "The security token included in the request is invalid."}, req: req, wantExitCode: exitInvalidCredentials, wantFix: "aws_access_key_id and aws_secret_access_key (or the credential source) of the profile \"sample-before-mfa\""},
+		{name: "S06: wrong secret", err: &smithy.GenericAPIError{Code: "SignatureDoesNotMatch", Message: "The request signature we calculated does not match the signature you provided."}, req: req, wantExitCode: exitInvalidCredentials, wantFix: "aws_secret_access_key"},
+		{name: "S07: region disabled", err: &smithy.GenericAPIError{Code: "RegionDisabledException", Message: "STS is not activated in this region for account:123456789012."}, req: req, wantExitCode: exitRegionDisabled, wantFix: "change region of the profile \"sample-before-mfa\" (or AWSMFA_ENDPOINT_REGION, --endpoint-region)"},
+		{name: "S08: duration too long", err: &smithy.GenericAPIError{Code: "ValidationError", Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role."}, req: req, wantExitCode: exitDurationTooLong, wantFix: "duration_seconds of the profile \"sample-before-mfa\""},
+		{name: "S09: throttling", err: fmt.Errorf("operation error: %w", &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}), req: req, wantExitCode: exitThrottling, wantFix: "Wait a moment"},
+		{name: "S10: unknown api error", err: &smithy.GenericAPIError{Code: "InternalFailure", Message: "unknown"}, req: req, wantExitCode: exitError, wantFix: ""},
+		{name: "S11: timeout", err: fmt.Errorf("operation error: %w", context.DeadlineExceeded), req: req, wantExitCode: exitTimeout, wantFix: "--timeout"},
+		{name: "S12: canceled", err: fmt.Errorf("operation error: %w", context.Canceled), req: req, wantExitCode: exitCanceled, wantFix: ""},
+		{name: "S13: not api error", err: errors.New("connection refused"), req: req, wantExitCode: exitError, wantFix: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifySTSError(tt.err, tt.req)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("classifySTSError() = %v, want to wrap %v", got, tt.err)
+			}
+			if code := exitCodeOf(got); code != tt.wantExitCode {
+				t.Errorf("exitCodeOf() = %v, want %v", code, tt.wantExitCode)
+			}
+			var se *stsError
+			if errors.As(got, &se) && !strings.Contains(se.fix, tt.wantFix) {
+				t.Errorf("classifySTSError() fix = %v, want to contain %v", se.fix, tt.wantFix)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// errInputCanceled is returned when the user cancels the input by Ctrl-C.
+var errInputCanceled = errors.New("the input was canceled")
+
+// terminal is the controlling terminal which the MFA token code is read from.
+// The code is read from the terminal, not from stdin, so that awsmfa works even if stdin is a pipe.
+type terminal struct {
+	ctx     context.Context
+	in      *os.File
+	out     io.Writer
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// openTerminal opens the controlling terminal. It fails if no terminal is available, such as in CI.
+// Inputs are canceled when ctx is done. timeout is how long to wait for each secret input, and 0 means no timeout.
+func openTerminal(ctx context.Context, timeout time.Duration) (*terminal, error) {
+	var in *os.File
+	var out io.Writer
+	var err error
+	if runtime.GOOS == "windows" {
+		in, err = os.Open("CONIN$")
+		out = os.Stderr
+	} else {
+		in, err = os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		out = in
+	}
+	if err != nil || !term.IsTerminal(int(in.Fd())) {
+		if in != nil {
+			in.Close()
+		}
+		return nil, fmt.Errorf("no terminal is available to input the MFA token code. Please run awsmfa in a terminal")
+	}
+	return &terminal{ctx: ctx, in: in, out: out, reader: bufio.NewReader(&contextReader{ctx: ctx, r: in}), timeout: timeout}, nil
+}
+
+// close closes the terminal.
+func (t *terminal) close() error {
+	return t.in.Close()
+}
+
+// readSecret reads a line without echo. It returns errInputCanceled when ctx is done, such as by Ctrl-C, and an error on timeout.
+// The state of the terminal is restored in both cases.
+func (t *terminal) readSecret() (string, error) {
+	fd := int(t.in.Fd())
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "ERROR", fmt.Errorf("failed to read input: %w", err)
+	}
+
+	type result struct {
+		b   []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		b, err := term.ReadPassword(fd)
+		done <- result{b: b, err: err}
+	}()
+
+	var timedOut <-chan time.Time
+	if t.timeout > 0 {
+		timer := time.NewTimer(t.timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+
+	select {
+	case r := <-done:
+		fmt.Fprintln(t.out)
+		if r.err != nil {
+			return "ERROR", fmt.Errorf("failed to read input: %w", r.err)
+		}
+		return strings.TrimSpace(string(r.b)), nil
+	case <-t.ctx.Done():
+		term.Restore(fd, state)
+		fmt.Fprintln(t.out)
+		return "ERROR", errInputCanceled
+	case <-timedOut:
+		term.Restore(fd, state)
+		fmt.Fprintln(t.out)
+		return "ERROR", fmt.Errorf("no input in %v", t.timeout)
+	}
+}
+
+// contextReader is a reader which returns an error when the context is done, even while the underlying reader blocks.
+// The blocked read is left behind, so it is only for reading the terminal just before awsmfa exits.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	buf := make([]byte, len(p))
+	done := make(chan result, 1)
+	go func() {
+		n, err := c.r.Read(buf)
+		done <- result{n: n, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		copy(p, buf[:r.n])
+		return r.n, r.err
+	case <-c.ctx.Done():
+		return 0, errInputCanceled
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func Test_contextReader(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := bufio.NewReader(&contextReader{ctx: ctx, r: pr})
+
+	go pw.Write([]byte("1h\n"))
+	if got, err := readLine(r); err != nil || got != "1h" {
+		t.Errorf("readLine() = %v, %v, want 1h", got, err)
+	}
+
+	cancel()
+	if _, err := readLine(r); !errors.Is(err, errInputCanceled) {
+		t.Errorf("readLine() error = %v, want %v", err, errInputCanceled)
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ini.v1"
+)
+
+// wait subcommand's input value
+var (
+	cliWaitProfile               string
+	cliWaitTimeout               time.Duration
+	cliWaitOutputProfile         string
+	cliWaitOutputCredentialsFile string
+	cliWaitFormat                string
+)
+
+// waitPollInterval is the interval to check the credentials file.
+const waitPollInterval = time.Second
+
+// NewCmdWait returns the wait command.
+func NewCmdWait() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until a profile has an active temporary token",
+		Long: `Wait until a profile has an active temporary token.
+
+awsmfa watches the credentials file, and exits successfully when the temporary token of the profile is saved,
+such as by awsmfa in another terminal. It exits with an error if the token doesn't appear before the timeout.`,
+		Example: `  awsmfa wait --profile sample --timeout 5m && aws s3 ls --profile sample`,
+		Args:    cobra.NoArgs,
+		RunE:    runWaitCmd,
+	}
+
+	cmd.Flags().StringVarP(&cliWaitProfile, "profile", "p", "", "The profile to wait for. The default value is 'default'")
+	cmd.Flags().DurationVar(&cliWaitTimeout, "timeout", 5*time.Minute, "How long to wait, such as 30s or 5m. 0 means no timeout.")
+	cmd.Flags().StringVar(&cliWaitOutputProfile, "output-profile", "", "The profile where temporary credentials are saved. The default value is the profile specified by --profile.")
+	cmd.Flags().StringVar(&cliWaitOutputCredentialsFile, "output-credentials-file", "", "The credentials file where temporary credentials are saved. The default value is the shared credentials file.")
+	cmd.Flags().StringVar(&cliWaitFormat, "format", "", "Print the result by a Go template such as '{{.Profile}} expires {{.Expiration}}' to stdout. See README for the data.")
+
+	return cmd
+}
+
+func runWaitCmd(cmd *cobra.Command, args []string) error {
+	// --format renders the result to stdout, and messages for humans are printed to stderr.
+	var tmpl *template.Template
+	if cmd.Flags().Changed("format") {
+		t, err := parseFormat(cliWaitFormat)
+		if err != nil {
+			return err
+		}
+		tmpl = t
+		humanOutput = os.Stderr
+	}
+
+	cred, err := ini.LooseLoad(credentialsFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load credentials file: %w", err)
+	}
+	cfg, err := ini.LooseLoad(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	awsmfaCfg, err := ini.Load(awsmfaCfgFilePath)
+	if err != nil {
+		awsmfaCfg = nil
+	}
+
+	projectCfg, _, err := loadProjectCfg(cliWaitProfile)
+	if err := skipProjectNotAllowed(err); err != nil {
+		return err
+	}
+
+	result := resolveOutputTarget(cliWaitProfile, cliWaitOutputProfile, cliWaitOutputCredentialsFile, cred, cfg, awsmfaCfg, projectCfg)
+	outputProfile, outputFile := result.Params["output_profile"].Value, result.Params["output_credentials_file"].Value
+	printBlue(fmt.Sprintf("Waiting for an active temporary token of the profile %v (%v) ...\n", outputProfile, outputFile))
+	due, err := waitForActiveToken(outputProfile, outputFile, cliWaitTimeout, waitPollInterval)
+	if tmpl != nil {
+		if err == nil {
+			result.Outcome, result.Expiration = outcomeActive, due
+			result.loadMetadata()
+		}
+		result.finish(err)
+		if perr := tmpl.Execute(os.Stdout, result); perr != nil {
+			printErrorRed(fmt.Errorf("failed to print the result: %w", perr))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	printCyan(fmt.Sprintf("Your temporary token is active for %v. Expired at %v\n", humanDuration(time.Until(*due)), due))
+	return nil
+}
+
+// resolveOutputTarget resolves the profile, and the profile and the credentials file where its temporary credentials are saved,
+// in the same way as awsmfa. The result has them as the params profile, before_mfa_profile, mode, output_profile and output_credentials_file.
+func resolveOutputTarget(cliProfile string, cliOutputProfile string, cliOutputFile string, cred *ini.File, cfg *ini.File, awsmfaCfg *ini.File, projectCfg *ini.File) *runResult {
+	result := newRunResult()
+	profile, s := setProfile(cliProfile, defaultProfile, awsmfaCfg, projectCfg)
+	result.Profile = profile
+	result.setParam("profile", profile, s)
+	beforeMFAProfile, s := setBeforeMFAProfile(profile, cred, cfg, awsmfaCfg)
+	result.setParam("before_mfa_profile", beforeMFAProfile, s)
+	if mode, s, err := setMode("", defaultMode, beforeMFAProfile, cred, cfg, awsmfaCfg, projectCfg); err == nil {
+		result.Mode = mode
+		result.setParam("mode", mode, s)
+	}
+	outputProfile, s := setOutputProfile(cliOutputProfile, profile, beforeMFAProfile, cred, cfg)
+	result.setParam("output_profile", outputProfile, s)
+	outputFile, s := setOutputCredentialsFile(cliOutputFile, credentialsFilePath, beforeMFAProfile, cred, cfg)
+	result.setParam("output_credentials_file", outputFile, s)
+	return result
+}
+
+// waitForActiveToken checks the credentials file every interval until the profile has an active token.
+// A missing or broken file is regarded as no token, because it may be being written.
+func waitForActiveToken(profile string, file string, timeout time.Duration, interval time.Duration) (due *time.Time, err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if output, err := ini.LooseLoad(file); err == nil {
+			if ok, due := hasActiveToken(profile, output, 0); ok {
+				return due, nil
+			}
+		}
+		sleep := interval
+		if timeout > 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, fmt.Errorf("timed out after %v waiting for an active temporary token of the profile %v in %v", timeout, profile, file)
+			}
+			if remaining < sleep {
+				sleep = remaining
+			}
+		}
+		time.Sleep(sleep)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+func Test_waitForActiveToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	expired := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	active := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("[sample]\nexpiration = %v\n", expired)), 0600); err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	if _, err := waitForActiveToken("sample", path, 50*time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Errorf("waitForActiveToken() error = nil, want timeout")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(path, []byte(fmt.Sprintf("[sample]\nexpiration = %v\n", active)), 0600)
+	}()
+	due, err := waitForActiveToken("sample", path, 5*time.Second, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForActiveToken() error = %v", err)
+	}
+	if due.UTC().Format(time.RFC3339) != active {
+		t.Errorf("waitForActiveToken() = %v, want %v", due, active)
+	}
+}
+
+func Test_resolveOutputTarget(t *testing.T) {
+	tests := []struct {
+		name              string
+		cliProfile        string
+		cliOutputProfile  string
+		env               string
+		wantOutputProfile string
+		wantOutputFile    string
+	}{
+		{name: "S01: keys of the before-mfa profile", cliProfile: "dev", wantOutputProfile: "dev-session", wantOutputFile: "testdata/resolveOutputTarget_output"},
+		{name: "S02: cli option", cliProfile: "dev", cliOutputProfile: "cli", wantOutputProfile: "cli", wantOutputFile: "testdata/resolveOutputTarget_output"},
+		{name: "S03: env", cliProfile: "prod", env: "env", wantOutputProfile: "env", wantOutputFile: "env"},
+		{name: "S04: build in default", cliProfile: "prod", wantOutputProfile: "prod", wantOutputFile: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(path string) { credentialsFilePath = path }(credentialsFilePath)
+			credentialsFilePath = "default"
+			defer os.Unsetenv("AWSMFA_OUTPUT_PROFILE")
+			defer os.Unsetenv("AWSMFA_OUTPUT_CREDENTIALS_FILE")
+			os.Setenv("AWSMFA_OUTPUT_PROFILE", tt.env)
+			os.Setenv("AWSMFA_OUTPUT_CREDENTIALS_FILE", tt.env)
+			cred, err := ini.Load("testdata/resolveOutputTarget_credentials")
+			if err != nil {
+				t.Fatalf("failed to load test data: %v", err)
+			}
+
+			result := resolveOutputTarget(tt.cliProfile, tt.cliOutputProfile, "", cred, ini.Empty(), nil, nil)
+			profile, outputProfile, outputFile := result.Profile, result.Params["output_profile"].Value, result.Params["output_credentials_file"].Value
+			if profile != tt.cliProfile || outputProfile != tt.wantOutputProfile || outputFile != tt.wantOutputFile {
+				t.Errorf("resolveOutputTarget() = %v, %v, %v, want %v, %v, %v", profile, outputProfile, outputFile, tt.cliProfile, tt.wantOutputProfile, tt.wantOutputFile)
+			}
+		})
+	}
+}